@@ -0,0 +1,239 @@
+package jzon
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// GetString returns the string at the path, or def if the node doesn't exist or isn't a string
+func (jz *Jzon) GetString(path string, def string) string {
+	if v, err := jz.Query(path); err == nil && v.Type == JzTypeStr {
+		return v.data.(string)
+	}
+	return def
+}
+
+// GetInt returns the integer at the path, or def if the node doesn't exist or isn't an integer
+func (jz *Jzon) GetInt(path string, def int64) int64 {
+	if v, err := jz.Query(path); err == nil && v.Type == JzTypeInt {
+		return v.data.(int64)
+	}
+	return def
+}
+
+// GetFloat returns the float at the path, or def if the node doesn't exist or isn't a number.
+// an integer is converted only if it can be represented exactly, see `AsFloat()` to convert any
+func (jz *Jzon) GetFloat(path string, def float64) float64 {
+	if v, err := jz.Query(path); err == nil {
+		if f, ok := exactFloat(v); ok {
+			return f
+		}
+	}
+	return def
+}
+
+// exactFloat returns the float of the node, an integer is converted only if
+// it can be represented exactly
+func exactFloat(v *Jzon) (f float64, ok bool) {
+	switch v.Type {
+	case JzTypeFlt:
+		return v.data.(float64), true
+	case JzTypeInt:
+		n := v.data.(int64)
+		if back, err := floatToInt(float64(n)); err == nil && back == n {
+			return float64(n), true
+		}
+	}
+	return 0, false
+}
+
+// GetBool returns the boolean at the path, or def if the node doesn't exist or isn't a boolean
+func (jz *Jzon) GetBool(path string, def bool) bool {
+	if v, err := jz.Query(path); err == nil && v.Type == JzTypeBol {
+		return v.data.(bool)
+	}
+	return def
+}
+
+// GetArray returns the array at the path, or def if the node doesn't exist or isn't an array
+func (jz *Jzon) GetArray(path string, def []*Jzon) []*Jzon {
+	if v, err := jz.Query(path); err == nil && v.Type == JzTypeArr {
+		return v.data.([]*Jzon)
+	}
+	return def
+}
+
+// MustString returns the string at the path, it panics if the node doesn't exist or isn't a string
+func (jz *Jzon) MustString(path string) string {
+	v := jz.mustQuery(path, JzTypeStr)
+	return v.data.(string)
+}
+
+// MustInt returns the integer at the path, it panics if the node doesn't exist or isn't an integer
+func (jz *Jzon) MustInt(path string) int64 {
+	v := jz.mustQuery(path, JzTypeInt)
+	return v.data.(int64)
+}
+
+// MustFloat returns the float at the path, it panics if the node doesn't exist or isn't
+// a number. an integer is converted only if it can be represented exactly, as `GetFloat()`
+func (jz *Jzon) MustFloat(path string) float64 {
+	v, err := jz.Query(path)
+	if err != nil {
+		panic(err)
+	}
+
+	f, ok := exactFloat(v)
+	switch {
+	case !ok && v.Type == JzTypeInt:
+		panic(fmt.Errorf("at `%s`: %d can not be represented as a float exactly", path, v.data.(int64)))
+	case !ok:
+		panic(fmt.Errorf("at `%s`: %v", path, expectTypeOf(JzTypeFlt, v.Type)))
+	}
+
+	return f
+}
+
+// MustBool returns the boolean at the path, it panics if the node doesn't exist or isn't a boolean
+func (jz *Jzon) MustBool(path string) bool {
+	v := jz.mustQuery(path, JzTypeBol)
+	return v.data.(bool)
+}
+
+// MustArray returns the array at the path, it panics if the node doesn't exist or isn't an array
+func (jz *Jzon) MustArray(path string) []*Jzon {
+	v := jz.mustQuery(path, JzTypeArr)
+	return v.data.([]*Jzon)
+}
+
+func (jz *Jzon) mustQuery(path string, t ValueType) *Jzon {
+	v, err := jz.Query(path)
+	if err != nil {
+		panic(err)
+	}
+
+	if v.Type != t {
+		panic(fmt.Errorf("at `%s`: %v", path, expectTypeOf(t, v.Type)))
+	}
+
+	return v
+}
+
+// AsInt converts the node at the path to an integer, a float is converted if it has
+// no fractional part and fits in int64. if lenient is set, a numeric string such as
+// "42" is parsed as well. otherwise an error will be thrown out
+func (jz *Jzon) AsInt(path string, lenient bool) (n int64, err error) {
+	v, err := jz.Query(path)
+	if err != nil {
+		return
+	}
+
+	switch {
+	case v.Type == JzTypeInt:
+		return v.data.(int64), nil
+
+	case v.Type == JzTypeFlt:
+		return floatToInt(v.data.(float64))
+
+	case v.Type == JzTypeStr && lenient:
+		s := v.data.(string)
+		if n, err = strconv.ParseInt(s, 10, 64); err == nil {
+			return n, nil
+		}
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return 0, fmt.Errorf("can not convert %q to an integer", s)
+		}
+		return floatToInt(f)
+	}
+
+	return 0, expectTypeOf(JzTypeInt, v.Type)
+}
+
+func floatToInt(f float64) (n int64, err error) {
+	if f != math.Trunc(f) || f < math.MinInt64 || f >= math.MaxInt64 {
+		return 0, fmt.Errorf("can not convert %v to an integer", f)
+	}
+
+	return int64(f), nil
+}
+
+// AsFloat converts the node at the path to a float, an integer is always converted.
+// if lenient is set, a numeric string such as "1.5" is parsed as well. otherwise
+// an error will be thrown out
+func (jz *Jzon) AsFloat(path string, lenient bool) (f float64, err error) {
+	v, err := jz.Query(path)
+	if err != nil {
+		return
+	}
+
+	switch {
+	case v.Type == JzTypeFlt:
+		return v.data.(float64), nil
+
+	case v.Type == JzTypeInt:
+		return float64(v.data.(int64)), nil
+
+	case v.Type == JzTypeStr && lenient:
+		s := v.data.(string)
+		if f, err = strconv.ParseFloat(s, 64); err != nil {
+			return 0, fmt.Errorf("can not convert %q to a float", s)
+		}
+		return f, nil
+	}
+
+	return 0, expectTypeOf(JzTypeFlt, v.Type)
+}
+
+// AsString converts the node at the path to a string, if lenient is set, numbers and
+// booleans are formatted as they are in JSON. otherwise an error will be thrown out
+func (jz *Jzon) AsString(path string, lenient bool) (s string, err error) {
+	v, err := jz.Query(path)
+	if err != nil {
+		return
+	}
+
+	switch {
+	case v.Type == JzTypeStr:
+		return v.data.(string), nil
+
+	case v.Type == JzTypeInt && lenient:
+		return strconv.FormatInt(v.data.(int64), 10), nil
+
+	case v.Type == JzTypeFlt && lenient:
+		return strconv.FormatFloat(v.data.(float64), 'g', -1, 64), nil
+
+	case v.Type == JzTypeBol && lenient:
+		return strconv.FormatBool(v.data.(bool)), nil
+	}
+
+	return "", expectTypeOf(JzTypeStr, v.Type)
+}
+
+// AsBool converts the node at the path to a boolean, if lenient is set, strings
+// accepted by `strconv.ParseBool()` are parsed, and numbers are true unless they
+// are zero. otherwise an error will be thrown out
+func (jz *Jzon) AsBool(path string, lenient bool) (b bool, err error) {
+	v, err := jz.Query(path)
+	if err != nil {
+		return
+	}
+
+	switch {
+	case v.Type == JzTypeBol:
+		return v.data.(bool), nil
+
+	case v.Type == JzTypeStr && lenient:
+		s := v.data.(string)
+		if b, err = strconv.ParseBool(s); err != nil {
+			return false, fmt.Errorf("can not convert %q to a boolean", s)
+		}
+		return b, nil
+
+	case isNumber(v) && lenient:
+		return toFloat(v) != 0, nil
+	}
+
+	return false, expectTypeOf(JzTypeBol, v.Type)
+}
@@ -0,0 +1,226 @@
+package jzon
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"hash"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// Canonical serializes the node in the JSON Canonicalization Scheme (RFC 8785),
+// the output is byte-stable, so it's suitable for signing and deduplication:
+// there is no whitespace, keys are sorted by UTF-16 code units, numbers are
+// formatted as ECMAScript does, and strings are escaped minimally. since all
+// numbers are IEEE 754 doubles in JCS, integers beyond MAX_SAFE_INTEGER lose
+// precision, see `CanonicalStrict()`. NaN and infinities, which can't be parsed
+// from JSON, are written as null
+func (jz *Jzon) Canonical() []byte {
+	var buf bytes.Buffer
+	jz.writeCanonical(&buf)
+	return buf.Bytes()
+}
+
+// MAX_SAFE_INTEGER is the largest integer n such that n and n+1 are both doubles,
+// as `Number.MAX_SAFE_INTEGER` of ECMAScript, integers beyond it are not I-JSON
+const MAX_SAFE_INTEGER = 1<<53 - 1
+
+// CanonicalStrict performs as `Canonical()`, except that if the node contains
+// an integer beyond ±MAX_SAFE_INTEGER, which would be written as the same number
+// as its neighbours, an error will be thrown out
+func (jz *Jzon) CanonicalStrict() (canonical []byte, err error) {
+	if err = jz.expectSafeIntegers(Root()); err != nil {
+		return
+	}
+
+	return jz.Canonical(), nil
+}
+
+// expectSafeIntegers reports the first integer beyond ±MAX_SAFE_INTEGER under p
+func (jz *Jzon) expectSafeIntegers(p *Path) (err error) {
+	switch jz.Type {
+	case JzTypeInt:
+		if n := jz.data.(int64); n > MAX_SAFE_INTEGER || n < -MAX_SAFE_INTEGER {
+			return fmt.Errorf("at `%s`: integer %d is beyond ±%d, which is not I-JSON", p, n, MAX_SAFE_INTEGER)
+		}
+
+	case JzTypeArr:
+		for i, elem := range jz.data.([]*Jzon) {
+			if err = elem.expectSafeIntegers(p.Index(i)); err != nil {
+				return
+			}
+		}
+
+	case JzTypeObj:
+		m := jz.data.(map[string]*Jzon)
+		for _, k := range jz.sortedKeys() {
+			if err = m[k].expectSafeIntegers(p.Key(k)); err != nil {
+				return
+			}
+		}
+	}
+
+	return nil
+}
+
+// Hash writes the canonical form of the node into h, as `Canonical()` does,
+// without building the whole form first, and returns the sum of h
+//
+//	sum := jz.Hash(sha256.New())
+func (jz *Jzon) Hash(h hash.Hash) (sum []byte) {
+	w := bufio.NewWriter(h)
+	jz.writeCanonical(w)
+	w.Flush()
+
+	return h.Sum(nil)
+}
+
+func (jz *Jzon) writeCanonical(w io.Writer) {
+	jz.writeSorted(w, true)
+}
+
+// writeSorted writes the node as `Canonical()` does if canonical is set,
+// otherwise integers are written exactly and floats always have a fraction
+// or an exponent, so the output can be parsed back into the same node
+func (jz *Jzon) writeSorted(w io.Writer, canonical bool) {
+	switch jz.Type {
+	case JzTypeObj:
+		m := jz.data.(map[string]*Jzon)
+		io.WriteString(w, "{")
+		for i, k := range utf16SortedKeys(m) {
+			if i > 0 {
+				io.WriteString(w, ",")
+			}
+			writeCanonicalString(w, k)
+			io.WriteString(w, ":")
+			m[k].writeSorted(w, canonical)
+		}
+		io.WriteString(w, "}")
+
+	case JzTypeArr:
+		io.WriteString(w, "[")
+		for i, elem := range jz.data.([]*Jzon) {
+			if i > 0 {
+				io.WriteString(w, ",")
+			}
+			elem.writeSorted(w, canonical)
+		}
+		io.WriteString(w, "]")
+
+	case JzTypeStr:
+		writeCanonicalString(w, jz.data.(string))
+
+	case JzTypeInt:
+		if canonical {
+			io.WriteString(w, formatECMAScript(toFloat(jz)))
+		} else {
+			io.WriteString(w, strconv.FormatInt(jz.data.(int64), 10))
+		}
+
+	case JzTypeFlt:
+		s := formatECMAScript(jz.data.(float64))
+		if !canonical && !strings.ContainsAny(s, ".en") {
+			// keeps a float without fractional part from being parsed as an integer
+			s += ".0"
+		}
+		io.WriteString(w, s)
+
+	case JzTypeBol:
+		io.WriteString(w, strconv.FormatBool(jz.data.(bool)))
+
+	default:
+		io.WriteString(w, "null")
+	}
+}
+
+// utf16SortedKeys sorts keys of the object by their UTF-16 code units
+func utf16SortedKeys(m map[string]*Jzon) []string {
+	type key struct {
+		k     string
+		units []uint16
+	}
+
+	var keys = make([]key, 0, len(m))
+	for k := range m {
+		keys = append(keys, key{k, utf16.Encode([]rune(k))})
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := keys[i].units, keys[j].units
+		for n := 0; n < len(a) && n < len(b); n++ {
+			if a[n] != b[n] {
+				return a[n] < b[n]
+			}
+		}
+		return len(a) < len(b)
+	})
+
+	var res = make([]string, len(keys))
+	for i := range keys {
+		res[i] = keys[i].k
+	}
+
+	return res
+}
+
+// writeCanonicalString escapes only quotes, backslashes and control characters,
+// with the short forms where JSON has them, and `\u00xx` in lower case otherwise
+func writeCanonicalString(w io.Writer, s string) {
+	const hex = "0123456789abcdef"
+
+	var buf = make([]byte, 0, len(s)+2)
+	buf = append(buf, '"')
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '"' || c == '\\':
+			buf = append(buf, '\\', c)
+		case c == '\b':
+			buf = append(buf, '\\', 'b')
+		case c == '\t':
+			buf = append(buf, '\\', 't')
+		case c == '\n':
+			buf = append(buf, '\\', 'n')
+		case c == '\f':
+			buf = append(buf, '\\', 'f')
+		case c == '\r':
+			buf = append(buf, '\\', 'r')
+		case c < 0x20:
+			buf = append(buf, '\\', 'u', '0', '0', hex[c>>4], hex[c&0xf])
+		default:
+			buf = append(buf, c)
+		}
+	}
+	buf = append(buf, '"')
+
+	w.Write(buf)
+}
+
+// formatECMAScript formats the number as `Number.prototype.toString()` of
+// ECMAScript does, which is the shortest form that round-trips, in plain
+// notation for magnitudes in [1e-6, 1e21), and in exponent notation otherwise
+func formatECMAScript(f float64) string {
+	switch {
+	case math.IsNaN(f) || math.IsInf(f, 0):
+		return "null"
+	case f == 0:
+		// negative zero is written as 0 as well
+		return "0"
+	}
+
+	if abs := math.Abs(f); abs >= 1e-6 && abs < 1e21 {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+
+	// Go writes at least two digits of the exponent, as in 1e-07
+	s := strconv.FormatFloat(f, 'e', -1, 64)
+	i := strings.IndexByte(s, 'e')
+	mantissa, sign, exp := s[:i], s[i+1], strings.TrimLeft(s[i+2:], "0")
+
+	return mantissa + "e" + string(sign) + exp
+}
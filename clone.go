@@ -0,0 +1,126 @@
+package jzon
+
+import "math"
+
+// Clone returns a shallow copy of the node, an object or an array gets its own
+// map or slice, so adding or removing children of the copy leaves the original
+// untouched, but the children themselves are shared with the original
+func (jz *Jzon) Clone() *Jzon {
+	var c = &Jzon{Type: jz.Type, data: jz.data}
+
+	switch jz.Type {
+	case JzTypeObj:
+		m := jz.data.(map[string]*Jzon)
+		cm := make(map[string]*Jzon, len(m))
+		for k, v := range m {
+			cm[k] = v
+		}
+		c.data = cm
+
+	case JzTypeArr:
+		arr := jz.data.([]*Jzon)
+		c.data = append(make([]*Jzon, 0, len(arr)), arr...)
+	}
+
+	return c
+}
+
+// DeepClone returns a deep copy of the node, no node is shared between the
+// copy and the original, so they can be modified independently
+func (jz *Jzon) DeepClone() *Jzon {
+	var c = &Jzon{Type: jz.Type, data: jz.data}
+
+	switch jz.Type {
+	case JzTypeObj:
+		m := jz.data.(map[string]*Jzon)
+		cm := make(map[string]*Jzon, len(m))
+		for k, v := range m {
+			cm[k] = v.DeepClone()
+		}
+		c.data = cm
+
+	case JzTypeArr:
+		arr := jz.data.([]*Jzon)
+		ca := make([]*Jzon, 0, len(arr))
+		for _, v := range arr {
+			ca = append(ca, v.DeepClone())
+		}
+		c.data = ca
+	}
+
+	return c
+}
+
+// EqualOptions controls how `EqualWith()` compares two nodes
+type EqualOptions struct {
+	// NumericEquivalence makes an integer equal to a float of the same value,
+	// such as 1 and 1.0, otherwise numbers of different types are not equal
+	NumericEquivalence bool
+}
+
+// Equal reports whether the node is structurally equal to other. objects are
+// equal if they have the same keys with equal values, regardless of the order
+// of keys, and arrays are equal if their elements are equal one by one
+func (jz *Jzon) Equal(other *Jzon) bool {
+	return jz.EqualWith(other, EqualOptions{})
+}
+
+// EqualWith performs as `Equal()`, with options for comparing
+func (jz *Jzon) EqualWith(other *Jzon, opts EqualOptions) bool {
+	if jz == nil || other == nil {
+		return jz == other
+	}
+
+	if jz.Type != other.Type {
+		if !opts.NumericEquivalence || !isNumber(jz) || !isNumber(other) {
+			return false
+		}
+		// one is an integer and the other is a float, they are compared exactly,
+		// since converting integers beyond 2^53 to floats loses precision
+		n, f := jz, other
+		if n.Type != JzTypeInt {
+			n, f = other, jz
+		}
+		return !math.IsNaN(f.data.(float64)) && compareIntFloat(n.data.(int64), f.data.(float64)) == 0
+	}
+
+	switch jz.Type {
+	case JzTypeNul:
+		return true
+
+	case JzTypeStr:
+		s1, _ := jz.String()
+		s2, _ := other.String()
+		return s1 == s2
+
+	case JzTypeInt, JzTypeFlt, JzTypeBol:
+		return jz.data == other.data
+
+	case JzTypeArr:
+		a1, a2 := jz.data.([]*Jzon), other.data.([]*Jzon)
+		if len(a1) != len(a2) {
+			return false
+		}
+		for i := range a1 {
+			if !a1[i].EqualWith(a2[i], opts) {
+				return false
+			}
+		}
+		return true
+
+	case JzTypeObj:
+		m1, m2 := jz.data.(map[string]*Jzon), other.data.(map[string]*Jzon)
+		if len(m1) != len(m2) {
+			return false
+		}
+		for k, v1 := range m1 {
+			v2, ok := m2[k]
+			if !ok || !v1.EqualWith(v2, opts) {
+				return false
+			}
+		}
+		return true
+	}
+
+	return false
+}
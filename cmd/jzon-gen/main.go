@@ -0,0 +1,115 @@
+// Command jzon-gen generates Go structures from JSON samples or a JSON Schema,
+// the structures can be decoded by `jzon.Decode()`. it's meant to be used
+// through `go generate`:
+//
+//	//go:generate go run github.com/zuoxinyu/jzon/cmd/jzon-gen -type Tweet -path .statuses -o tweet.go testdata/twitter.json
+//
+// each input file is a sample, and with -path, the node at the path of each
+// file is, or the elements of it are if it's an array. with -schema, the only
+// input file is a schema such as one made by `jzon.InferSchema()`
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/zuoxinyu/jzon"
+)
+
+func main() {
+	var (
+		typeName = flag.String("type", "", "name of the generated structure (required)")
+		pkg      = flag.String("pkg", os.Getenv("GOPACKAGE"), "package of the generated source, $GOPACKAGE by default")
+		output   = flag.String("o", "", "output file, the standard output by default")
+		tag      = flag.String("tag", jzon.TAG_NAME, "tag naming the key of each field")
+		valid    = flag.Bool("valid", false, "emit `valid:` tags as well")
+		schema   = flag.Bool("schema", false, "the input file is a JSON Schema instead of samples")
+		path     = flag.String("path", "", "path of the samples in each input file, such as `.items`")
+	)
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: jzon-gen -type Name [flags] file...\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if *typeName == "" || flag.NArg() == 0 || (*schema && flag.NArg() != 1) {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if *pkg == "" {
+		*pkg = "main"
+	}
+
+	if err := run(*typeName, *output, *path, *schema, jzon.GenOptions{
+		Package:   *pkg,
+		TagName:   *tag,
+		ValidTags: *valid,
+	}); err != nil {
+		fmt.Fprintf(os.Stderr, "jzon-gen: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(typeName, output, path string, schema bool, opts jzon.GenOptions) (err error) {
+	var samples []*jzon.Jzon
+	for _, file := range flag.Args() {
+		var nodes []*jzon.Jzon
+		if nodes, err = load(file, path); err != nil {
+			return
+		}
+		samples = append(samples, nodes...)
+	}
+
+	var src []byte
+	if schema {
+		src, err = jzon.GenerateStructs(typeName, samples[0], opts)
+	} else {
+		src, err = jzon.GenerateStructsFromSamples(typeName, opts, samples...)
+	}
+	if err != nil {
+		return
+	}
+
+	src = append([]byte("// Code generated by jzon-gen. DO NOT EDIT.\n\n"), src...)
+	if output == "" {
+		_, err = os.Stdout.Write(src)
+		return
+	}
+
+	return os.WriteFile(output, src, 0644)
+}
+
+// load parses the file, and returns the samples at the path of it
+func load(file, path string) (nodes []*jzon.Jzon, err error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return
+	}
+
+	jz, err := jzon.Parse(content)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", file, err)
+	}
+
+	if path == "" {
+		return []*jzon.Jzon{jz}, nil
+	}
+
+	// `$` is expanded by `go generate`, so the leading `$` may be left out
+	if !strings.HasPrefix(path, "$") {
+		path = "$" + path
+	}
+
+	if jz, err = jz.Query(path); err != nil {
+		return nil, fmt.Errorf("%s: %v", file, err)
+	}
+
+	if arr, err := jz.Array(); err == nil {
+		return arr, nil
+	}
+
+	return []*jzon.Jzon{jz}, nil
+}
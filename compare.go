@@ -0,0 +1,146 @@
+package jzon
+
+import (
+	"math"
+	"strings"
+)
+
+func isNumber(jz *Jzon) bool {
+	return jz.Type == JzTypeInt || jz.Type == JzTypeFlt
+}
+
+func toFloat(jz *Jzon) float64 {
+	if jz.Type == JzTypeInt {
+		return float64(jz.data.(int64))
+	}
+
+	return jz.data.(float64)
+}
+
+// typeOrders defines the order between different types, which is the same as jq:
+// null < false < true < numbers < strings < arrays < objects
+var typeOrders = map[ValueType]int{
+	JzTypeNul: 0,
+	JzTypeBol: 1,
+	JzTypeInt: 2,
+	JzTypeFlt: 2,
+	JzTypeStr: 3,
+	JzTypeArr: 4,
+	JzTypeObj: 5,
+}
+
+// Compare returns -1, 0 or 1 when a is less than, equal to or greater than b, it
+// defines a total ordering across all JSON values, so arrays of mixed types can
+// be sorted. values of different types are ordered by typeOrders, integers and
+// floats are compared by values, strings are compared byte-wise, arrays are
+// compared element by element, and objects are compared by their sorted keys
+// first, then values key by key
+func Compare(a, b *Jzon) int {
+	if oa, ob := typeOrders[a.Type], typeOrders[b.Type]; oa != ob {
+		return compareInts(oa, ob)
+	}
+
+	switch a.Type {
+	case JzTypeBol:
+		return compareInts(boolToInt(a.data.(bool)), boolToInt(b.data.(bool)))
+
+	case JzTypeInt, JzTypeFlt:
+		switch {
+		case a.Type == JzTypeInt && b.Type == JzTypeInt:
+			return compareInts64(a.data.(int64), b.data.(int64))
+		case a.Type == JzTypeInt:
+			return compareIntFloat(a.data.(int64), b.data.(float64))
+		case b.Type == JzTypeInt:
+			return -compareIntFloat(b.data.(int64), a.data.(float64))
+		}
+		fa, fb := a.data.(float64), b.data.(float64)
+		switch {
+		case fa < fb:
+			return -1
+		case fa > fb:
+			return 1
+		}
+		return 0
+
+	case JzTypeStr:
+		return strings.Compare(a.data.(string), b.data.(string))
+
+	case JzTypeArr:
+		aa, ba := a.data.([]*Jzon), b.data.([]*Jzon)
+		for i := 0; i < len(aa) && i < len(ba); i++ {
+			if c := Compare(aa[i], ba[i]); c != 0 {
+				return c
+			}
+		}
+		return compareInts(len(aa), len(ba))
+
+	case JzTypeObj:
+		// objects are compared by their sorted keys first, then values key by key
+		ak, bk := a.sortedKeys(), b.sortedKeys()
+		for i := 0; i < len(ak) && i < len(bk); i++ {
+			if c := strings.Compare(ak[i], bk[i]); c != 0 {
+				return c
+			}
+		}
+		if c := compareInts(len(ak), len(bk)); c != 0 {
+			return c
+		}
+		am, bm := a.data.(map[string]*Jzon), b.data.(map[string]*Jzon)
+		for _, k := range ak {
+			if c := Compare(am[k], bm[k]); c != 0 {
+				return c
+			}
+		}
+	}
+
+	return 0
+}
+
+func compareInts(a, b int) int {
+	return compareInts64(int64(a), int64(b))
+}
+
+func compareInts64(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// compareIntFloat compares an integer with a float exactly, since converting
+// integers beyond 2^53 to floats loses precision, and the order would not be
+// transitive any more. NaN equals to any integer, as it does to any float
+func compareIntFloat(i int64, f float64) int {
+	switch {
+	case math.IsNaN(f):
+		return 0
+	case f >= math.MaxInt64:
+		// float64(math.MaxInt64) is 2^63, which is out of range
+		return -1
+	case f < math.MinInt64:
+		return 1
+	}
+
+	t := math.Trunc(f)
+	if c := compareInts64(i, int64(t)); c != 0 {
+		return c
+	}
+
+	switch {
+	case f > t:
+		return -1
+	case f < t:
+		return 1
+	}
+	return 0
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
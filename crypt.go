@@ -0,0 +1,171 @@
+package jzon
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+// Members of the envelope object which replaces an encrypted value
+const (
+	ENVELOPE_NONCE      = "nonce"
+	ENVELOPE_CIPHERTEXT = "ciphertext"
+)
+
+// NewAESGCM returns the AES-GCM cipher of the key, which is the default AEAD
+// of `EncryptPaths()`, the key must be of 16, 24 or 32 bytes
+func NewAESGCM(key []byte) (aead cipher.AEAD, err error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// EncryptPaths replaces each node selected by the paths with an envelope object
+// `{"nonce": "...", "ciphertext": "..."}`, holding a random nonce and the sealed
+// compact JSON of the node in base64. the concrete path of the node, such as
+// `$['user']['ssn']`, is authenticated along with it, so an envelope can't be
+// moved to another path. paths may contain wildcards, and those which select
+// nothing are skipped. all or none of the nodes are encrypted, if any path is
+// malformed or sealing fails, an error will be thrown out
+//
+//	aead, _ := jzon.NewAESGCM(key)
+//	err := jzon.EncryptPaths(jz, []string{"$.user.ssn", "$.cards[*].number"}, aead)
+func EncryptPaths(jz *Jzon, paths []string, aead cipher.AEAD) (err error) {
+	if aead == nil {
+		return errors.New("expect an AEAD cipher, but found nil")
+	}
+
+	return replacePaths(jz, paths, func(m Match) (res *Jzon, err error) {
+		var plaintext bytes.Buffer
+		m.Node.writeSorted(&plaintext, false)
+
+		nonce := make([]byte, aead.NonceSize())
+		if _, err = rand.Read(nonce); err != nil {
+			return
+		}
+
+		res = New(JzTypeObj)
+		res.Insert(ENVELOPE_NONCE, NewFromAny(base64.StdEncoding.EncodeToString(nonce)))
+		res.Insert(ENVELOPE_CIPHERTEXT, NewFromAny(base64.StdEncoding.EncodeToString(
+			aead.Seal(nil, nonce, plaintext.Bytes(), []byte(m.Path)))))
+
+		return res, nil
+	})
+}
+
+// DecryptPaths reverses `EncryptPaths()`, each node selected by the paths must
+// be an envelope sealed at the same path by the same key. all or none of the
+// nodes are decrypted, if any envelope is malformed or fails authentication,
+// an error will be thrown out
+func DecryptPaths(jz *Jzon, paths []string, aead cipher.AEAD) (err error) {
+	if aead == nil {
+		return errors.New("expect an AEAD cipher, but found nil")
+	}
+
+	return replacePaths(jz, paths, func(m Match) (res *Jzon, err error) {
+		member := func(k string) (b []byte, err error) {
+			v, err := m.Node.ValueOf(k)
+			if err != nil {
+				return nil, fmt.Errorf("missing member `%s` in the envelope", k)
+			}
+			s, err := v.String()
+			if err != nil {
+				return
+			}
+			return base64.StdEncoding.DecodeString(s)
+		}
+
+		if m.Node.Type != JzTypeObj {
+			return nil, expectTypeOf(JzTypeObj, m.Node.Type)
+		}
+
+		nonce, err := member(ENVELOPE_NONCE)
+		if err != nil {
+			return
+		}
+		if len(nonce) != aead.NonceSize() {
+			return nil, fmt.Errorf("expect a nonce of %d bytes, but found %d bytes", aead.NonceSize(), len(nonce))
+		}
+
+		ciphertext, err := member(ENVELOPE_CIPHERTEXT)
+		if err != nil {
+			return
+		}
+
+		plaintext, err := aead.Open(nil, nonce, ciphertext, []byte(m.Path))
+		if err != nil {
+			return
+		}
+
+		return Parse(plaintext)
+	})
+}
+
+// replacePaths replaces nodes selected by the paths with the results of fn, all
+// of them are converted first, so if any conversion fails, the node is left
+// unchanged. a node is replaced in its parent rather than overwritten, and the
+// containers on the way from the root are copied once, so nodes shared with
+// other documents, such as those of a `Clone()`, are left unchanged
+func replacePaths(jz *Jzon, paths []string, fn func(m Match) (*Jzon, error)) (err error) {
+	type replacement struct {
+		segs []segment
+		res  *Jzon
+	}
+
+	var reps []replacement
+	for _, path := range paths {
+		var p *Path
+		if p, err = CompilePath(path); err != nil {
+			return
+		}
+
+		for _, m := range p.Matches(jz) {
+			var res *Jzon
+			if res, err = fn(m); err != nil {
+				return fmt.Errorf("at `%s`: %v", m.Path, err)
+			}
+			// the normalized path always compiles
+			concrete, _ := CompilePath(m.Path)
+			reps = append(reps, replacement{concrete.segs, res})
+		}
+	}
+
+	var copied = map[*Jzon]bool{jz: true}
+	for _, r := range reps {
+		if len(r.segs) == 0 {
+			*jz = *r.res
+			continue
+		}
+
+		// a node under another replaced node, which has been converted along with
+		// it, is not found any more
+		cur, found := jz, true
+		for _, seg := range r.segs[:len(r.segs)-1] {
+			child, err := cur.child(seg)
+			if err != nil {
+				found = false
+				break
+			}
+			if !copied[child] {
+				child = child.Clone()
+				copied[child] = true
+				cur.setChild(seg, child, false)
+			}
+			cur = child
+		}
+
+		last := r.segs[len(r.segs)-1]
+		if _, err := cur.child(last); found && err == nil {
+			cur.setChild(last, r.res, false)
+		}
+	}
+
+	return nil
+}
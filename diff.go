@@ -0,0 +1,308 @@
+package jzon
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Operations of JSON Patch (RFC 6902)
+const (
+	PATCH_ADD     = "add"
+	PATCH_REMOVE  = "remove"
+	PATCH_REPLACE = "replace"
+	PATCH_MOVE    = "move"
+	PATCH_COPY    = "copy"
+	PATCH_TEST    = "test"
+)
+
+// PatchOp is a single operation of a JSON Patch, Path and From are JSON Pointers
+type PatchOp struct {
+	Op    string
+	Path  string
+	From  string // only for move and copy
+	Value *Jzon  // only for add, replace and test
+
+	old    *Jzon // the value being removed or replaced, for printing
+	member bool  // whether Path refers to a member of an object
+}
+
+// Patch is a JSON Patch document, a sequence of operations applied in order
+type Patch []PatchOp
+
+// DiffOptions controls how `DiffWith()` compares two trees
+type DiffOptions struct {
+	// ArrayLCS compares arrays by their longest common subsequence, so inserting
+	// or removing an element in the middle doesn't produce changes for all the
+	// elements after it. otherwise arrays are compared index by index
+	ArrayLCS bool
+
+	// DetectMoves turns a removal and an addition of equal values into a move,
+	// only members of objects are taken into account
+	DetectMoves bool
+
+	// DetectCopies turns an addition of a non-empty object or array into a copy,
+	// if an equal value stays unchanged in both trees
+	DetectCopies bool
+}
+
+// Diff compares two trees and returns a JSON Patch which turns a into b, arrays
+// are compared index by index. values in the patch are shared with b
+func Diff(a, b *Jzon) Patch {
+	return DiffWith(a, b, DiffOptions{})
+}
+
+// DiffWith performs as `Diff()`, with options for comparing
+func DiffWith(a, b *Jzon, opts DiffOptions) Patch {
+	d := differ{opts: opts}
+	d.diff(nil, false, a, b)
+
+	if opts.DetectMoves {
+		d.detectMoves()
+	}
+
+	if opts.DetectCopies {
+		d.detectCopies(a, b)
+	}
+
+	return d.patch
+}
+
+type differ struct {
+	opts  DiffOptions
+	patch Patch
+}
+
+func (d *differ) emit(op PatchOp) {
+	d.patch = append(d.patch, op)
+}
+
+func (d *differ) diff(tokens []string, member bool, a, b *Jzon) {
+	switch {
+	case a.Equal(b):
+		return
+
+	case a.Type == JzTypeObj && b.Type == JzTypeObj:
+		d.diffObject(tokens, a, b)
+
+	case a.Type == JzTypeArr && b.Type == JzTypeArr && d.opts.ArrayLCS:
+		d.diffArrayLCS(tokens, a.data.([]*Jzon), b.data.([]*Jzon))
+
+	case a.Type == JzTypeArr && b.Type == JzTypeArr:
+		d.diffArray(tokens, a.data.([]*Jzon), b.data.([]*Jzon))
+
+	default:
+		d.emit(PatchOp{Op: PATCH_REPLACE, Path: formatPointer(tokens), Value: b, old: a, member: member})
+	}
+}
+
+func (d *differ) diffObject(tokens []string, a, b *Jzon) {
+	am, bm := a.data.(map[string]*Jzon), b.data.(map[string]*Jzon)
+
+	for _, k := range a.sortedKeys() {
+		child := append(tokens, k)
+		if bv, ok := bm[k]; ok {
+			d.diff(child, true, am[k], bv)
+		} else {
+			d.emit(PatchOp{Op: PATCH_REMOVE, Path: formatPointer(child), old: am[k], member: true})
+		}
+	}
+
+	for _, k := range b.sortedKeys() {
+		if _, ok := am[k]; !ok {
+			d.emit(PatchOp{Op: PATCH_ADD, Path: formatPointer(append(tokens, k)), Value: bm[k], member: true})
+		}
+	}
+}
+
+func (d *differ) diffArray(tokens []string, a, b []*Jzon) {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		d.diff(append(tokens, strconv.Itoa(i)), false, a[i], b[i])
+	}
+
+	// remove from the tail, so that indices of the remaining ones don't change
+	for i := len(a) - 1; i >= len(b); i-- {
+		d.emit(PatchOp{Op: PATCH_REMOVE, Path: formatPointer(append(tokens, strconv.Itoa(i))), old: a[i]})
+	}
+
+	for i := len(a); i < len(b); i++ {
+		d.emit(PatchOp{Op: PATCH_ADD, Path: formatPointer(append(tokens, strconv.Itoa(i))), Value: b[i]})
+	}
+}
+
+func (d *differ) diffArrayLCS(tokens []string, a, b []*Jzon) {
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i].Equal(b[j]) {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	// idx is the index in the array being patched, removed elements between
+	// two common elements are paired with added ones, and diffed in place
+	var idx int
+	var removed, added []*Jzon
+	flush := func() {
+		var n = min(len(removed), len(added))
+		for k := 0; k < n; k++ {
+			d.diff(append(tokens, strconv.Itoa(idx)), false, removed[k], added[k])
+			idx++
+		}
+		for _, v := range removed[n:] {
+			d.emit(PatchOp{Op: PATCH_REMOVE, Path: formatPointer(append(tokens, strconv.Itoa(idx))), old: v})
+		}
+		for _, v := range added[n:] {
+			d.emit(PatchOp{Op: PATCH_ADD, Path: formatPointer(append(tokens, strconv.Itoa(idx))), Value: v})
+			idx++
+		}
+		removed, added = removed[:0], added[:0]
+	}
+
+	var i, j int
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i].Equal(b[j]):
+			flush()
+			idx++
+			i++
+			j++
+		case j == len(b) || i < len(a) && lcs[i+1][j] >= lcs[i][j+1]:
+			removed = append(removed, a[i])
+			i++
+		default:
+			added = append(added, b[j])
+			j++
+		}
+	}
+	flush()
+}
+
+// detectMoves pairs each addition of an object member with a former removal
+// of an equal object member, the removal is dropped and the addition becomes
+// a move. removals of object members never shift the following operations
+func (d *differ) detectMoves() {
+	var dropped = make(map[int]bool)
+
+	for k, add := range d.patch {
+		if add.Op != PATCH_ADD || !add.member {
+			continue
+		}
+
+		for r, rm := range d.patch[:k] {
+			if rm.Op == PATCH_REMOVE && rm.member && !dropped[r] && rm.old.Equal(add.Value) {
+				dropped[r] = true
+				d.patch[k] = PatchOp{Op: PATCH_MOVE, Path: add.Path, From: rm.Path, Value: add.Value, member: true}
+				break
+			}
+		}
+	}
+
+	var patch = d.patch[:0]
+	for k, op := range d.patch {
+		if !dropped[k] {
+			patch = append(patch, op)
+		}
+	}
+	d.patch = patch
+}
+
+// detectCopies turns additions into copies from values which stay unchanged.
+// only values reached through object members are candidates, so that no
+// operation can shift or modify them before the copy is applied
+func (d *differ) detectCopies(a, b *Jzon) {
+	var sources []PatchOp
+	var collect func(tokens []string, a, b *Jzon)
+	collect = func(tokens []string, a, b *Jzon) {
+		if a.Equal(b) {
+			if l, _ := a.Length(); l > 0 {
+				sources = append(sources, PatchOp{Path: formatPointer(tokens), Value: a})
+			}
+			return
+		}
+
+		if a.Type == JzTypeObj && b.Type == JzTypeObj {
+			am, bm := a.data.(map[string]*Jzon), b.data.(map[string]*Jzon)
+			for _, k := range a.sortedKeys() {
+				if bv, ok := bm[k]; ok {
+					collect(append(tokens, k), am[k], bv)
+				}
+			}
+		}
+	}
+	collect(nil, a, b)
+
+	for k, op := range d.patch {
+		if op.Op != PATCH_ADD {
+			continue
+		}
+
+		for _, src := range sources {
+			if src.Value.Equal(op.Value) {
+				d.patch[k] = PatchOp{Op: PATCH_COPY, Path: op.Path, From: src.Path, Value: op.Value, member: op.member}
+				break
+			}
+		}
+	}
+}
+
+// Jzon converts the patch to a JSON Patch document
+func (p Patch) Jzon() *Jzon {
+	var doc = New(JzTypeArr)
+	for _, op := range p {
+		obj := New(JzTypeObj)
+		obj.Insert("op", NewFromAny(op.Op))
+		obj.Insert("path", NewFromAny(op.Path))
+
+		switch op.Op {
+		case PATCH_MOVE, PATCH_COPY:
+			obj.Insert("from", NewFromAny(op.From))
+		case PATCH_ADD, PATCH_REPLACE, PATCH_TEST:
+			obj.Insert("value", op.Value)
+		}
+
+		doc.Append(obj)
+	}
+
+	return doc
+}
+
+// String prints the patch as a human-readable diff, one operation per line,
+// which begins with a mark of the operation: `+` for add, `-` for remove, `~`
+// for replace as `~ /path: old => new`, `>` for move as `> /from => /path`, `&`
+// for copy and `?` for test
+func (p Patch) String() string {
+	var sb strings.Builder
+	for _, op := range p {
+		switch op.Op {
+		case PATCH_ADD:
+			sb.WriteString("+ " + op.Path + ": " + op.Value.Compact())
+		case PATCH_REMOVE:
+			sb.WriteString("- " + op.Path)
+			if op.old != nil {
+				sb.WriteString(": " + op.old.Compact())
+			}
+		case PATCH_REPLACE:
+			sb.WriteString("~ " + op.Path + ": ")
+			if op.old != nil {
+				sb.WriteString(op.old.Compact() + " => ")
+			}
+			sb.WriteString(op.Value.Compact())
+		case PATCH_MOVE:
+			sb.WriteString("> " + op.From + " => " + op.Path)
+		case PATCH_COPY:
+			sb.WriteString("& " + op.From + " => " + op.Path)
+		case PATCH_TEST:
+			sb.WriteString("? " + op.Path + ": " + op.Value.Compact())
+		}
+		sb.WriteByte('\n')
+	}
+
+	return sb.String()
+}
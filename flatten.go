@@ -0,0 +1,273 @@
+package jzon
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// FlattenOptions controls how `FlattenWith()` and `UnflattenWith()` join and
+// split keys
+type FlattenOptions struct {
+	// Separator joins keys of nested objects, it's "." by default
+	Separator string
+
+	// BracketIndex writes array indices as `a.b[0]`, otherwise as `a.b.0`, in
+	// which case segments of digits are always indices at unflattening
+	BracketIndex bool
+
+	// Escape escapes keys with backslashes as `parsePathKey()` does, so keys
+	// containing the separator, brackets or backslashes can be restored, with
+	// "." as the separator and BracketIndex set, a flattened key prefixed with
+	// "$." is a path which `Query()` accepts. the empty key is written as
+	// EMPTY_FLATTEN_KEY, so it can be restored at any depth
+	Escape bool
+}
+
+func (opts FlattenOptions) separator() string {
+	if opts.Separator == "" {
+		return "."
+	}
+	return opts.Separator
+}
+
+// Flatten turns nested objects and arrays into a map from joined keys to leaf values,
+// `{"a":{"b":[1]}}` becomes `{"a.b.0":1}` with "." as sep. empty objects and arrays
+// are leaves as well, and the values are shared with the node
+func (jz *Jzon) Flatten(sep string) map[string]*Jzon {
+	return jz.FlattenWith(FlattenOptions{Separator: sep})
+}
+
+// FlattenWith performs as `Flatten()`, with options for joining keys
+func (jz *Jzon) FlattenWith(opts FlattenOptions) (flat map[string]*Jzon) {
+	flat = make(map[string]*Jzon)
+	jz.flatten("", opts, flat)
+	return flat
+}
+
+func (jz *Jzon) flatten(prefix string, opts FlattenOptions, flat map[string]*Jzon) {
+	switch l, _ := jz.Length(); {
+	case l <= 0:
+		// scalars, empty objects and empty arrays are leaves
+		flat[prefix] = jz
+
+	case jz.Type == JzTypeArr:
+		for i, v := range jz.data.([]*Jzon) {
+			var key string
+			switch {
+			case opts.BracketIndex:
+				key = prefix + "[" + strconv.Itoa(i) + "]"
+			case prefix == "":
+				key = strconv.Itoa(i)
+			default:
+				key = prefix + opts.separator() + strconv.Itoa(i)
+			}
+			v.flatten(key, opts, flat)
+		}
+
+	case jz.Type == JzTypeObj:
+		for k, v := range jz.data.(map[string]*Jzon) {
+			if opts.Escape {
+				k = escapeFlattenKey(k, opts.separator())
+			}
+			if prefix != "" {
+				k = prefix + opts.separator() + k
+			}
+			v.flatten(k, opts, flat)
+		}
+	}
+}
+
+// EMPTY_FLATTEN_KEY is the escaped form of the empty key, which is written as
+// nothing otherwise, so `{"":1}` would be flattened to the same key as the scalar 1
+const EMPTY_FLATTEN_KEY = `""`
+
+// escapeFlattenKey escapes a key as `escapePathKey()`, and escapes quotes and
+// each byte of sep as well, the empty key is written as EMPTY_FLATTEN_KEY
+func escapeFlattenKey(k string, sep string) string {
+	if k == "" {
+		return EMPTY_FLATTEN_KEY
+	}
+
+	var escaped = make([]byte, 0, len(k))
+	for i := 0; i < len(k); i++ {
+		if strings.IndexByte(".[];\\\"", k[i]) >= 0 || strings.IndexByte(sep, k[i]) >= 0 {
+			escaped = append(escaped, '\\')
+		}
+		escaped = append(escaped, k[i])
+	}
+
+	return string(escaped)
+}
+
+// UNFLATTEN_MAX_GAP limits how far an index of `Unflatten()` can be beyond the
+// number of keys, so a malformed key such as "a.1000000000" can't make a huge array
+const UNFLATTEN_MAX_GAP = 1024
+
+// Unflatten rebuilds nested objects and arrays from a map produced by `Flatten()`,
+// segments of digits become array indices, and missing elements are padded with
+// null. if two keys conflict, such as "a" and "a.b", even if "a" holds an empty
+// object, or an index is negative or beyond the number of keys by more than
+// UNFLATTEN_MAX_GAP, an error will be thrown out
+func Unflatten(flat map[string]*Jzon, sep string) (jz *Jzon, err error) {
+	return UnflattenWith(flat, FlattenOptions{Separator: sep})
+}
+
+// UnflattenWith performs as `Unflatten()`, with options for splitting keys
+func UnflattenWith(flat map[string]*Jzon, opts FlattenOptions) (jz *Jzon, err error) {
+	type entry struct {
+		key  string
+		segs []segment
+	}
+
+	var entries = make([]entry, 0, len(flat))
+	for k := range flat {
+		segs, err := splitFlattenKey(k, opts, len(flat)+UNFLATTEN_MAX_GAP)
+		if err != nil {
+			return nil, fmt.Errorf("can not unflatten key `%s`: %v", k, err)
+		}
+		entries = append(entries, entry{k, segs})
+	}
+
+	// arrays grow in the order of indices, and a key comes before the keys under it
+	sort.Slice(entries, func(i, j int) bool {
+		return compareSegments(entries[i].segs, entries[j].segs) < 0
+	})
+
+	// placed maps paths of the leaves to their keys, a leaf is never a container
+	// of other keys, even if it's an empty object or array
+	var placed = make(map[string]string)
+	for _, e := range entries {
+		if len(e.segs) == 0 {
+			if len(entries) > 1 {
+				return nil, fmt.Errorf("the empty key conflicts with other keys")
+			}
+			return flat[e.key], nil
+		}
+
+		for i := 1; i <= len(e.segs); i++ {
+			if k, ok := placed[(&Path{segs: e.segs[:i]}).String()]; ok {
+				return nil, fmt.Errorf("key `%s` conflicts with key `%s`", e.key, k)
+			}
+		}
+		placed[(&Path{segs: e.segs}).String()] = e.key
+
+		if jz == nil && e.segs[0].kind == _SegIndex {
+			jz = New(JzTypeArr)
+		} else if jz == nil {
+			jz = New(JzTypeObj)
+		}
+
+		if err = (&Path{segs: e.segs}).SetCreate(jz, flat[e.key]); err != nil {
+			return nil, fmt.Errorf("can not unflatten key `%s`: %v", e.key, err)
+		}
+	}
+
+	if jz == nil {
+		jz = New(JzTypeObj)
+	}
+
+	return jz, nil
+}
+
+// compareSegments orders segments one by one, keys as strings and indices as numbers
+func compareSegments(a, b []segment) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		switch {
+		case a[i].kind != b[i].kind:
+			return compareInts(int(a[i].kind), int(b[i].kind))
+		case a[i].kind == _SegIndex && a[i].index != b[i].index:
+			return compareInts(a[i].index, b[i].index)
+		case a[i].kind == _SegKey && a[i].key != b[i].key:
+			return strings.Compare(a[i].key, b[i].key)
+		}
+	}
+
+	return compareInts(len(a), len(b))
+}
+
+// splitFlattenKey splits a flattened key to path segments, indices must be less than limit
+func splitFlattenKey(k string, opts FlattenOptions, limit int) (segs []segment, err error) {
+	var sep = opts.separator()
+	var buf []byte
+	var pending = false
+
+	// flush emits the buffered key, which may be an index in the dotted style
+	flush := func() {
+		if !pending {
+			return
+		}
+
+		s := string(buf)
+		if n, e := strconv.Atoi(s); e == nil && !opts.BracketIndex && n >= 0 && strconv.Itoa(n) == s {
+			if n >= limit {
+				err = fmt.Errorf("index %d is too far beyond the number of keys", n)
+			}
+			segs = append(segs, segment{kind: _SegIndex, index: n})
+		} else {
+			segs = append(segs, segment{kind: _SegKey, key: s})
+		}
+		buf, pending = buf[:0], false
+	}
+
+	for i := 0; i < len(k); {
+		switch {
+		case opts.Escape && len(buf) == 0 && isEmptyFlattenKey(k[i:], sep, opts.BracketIndex):
+			segs = append(segs, segment{kind: _SegKey, key: ""})
+			pending = false
+			i += len(EMPTY_FLATTEN_KEY)
+
+		case opts.Escape && k[i] == '\\' && i+1 < len(k):
+			buf = append(buf, k[i+1])
+			pending = true
+			i += 2
+
+		case strings.HasPrefix(k[i:], sep):
+			flush()
+			pending = true
+			i += len(sep)
+
+		case opts.BracketIndex && k[i] == '[' && strings.IndexByte(k[i:], ']') > 1:
+			end := i + strings.IndexByte(k[i:], ']')
+			n, err := strconv.Atoi(k[i+1 : end])
+			if err != nil {
+				// not an index, such as `a[b]`, just a part of the key
+				buf = append(buf, k[i])
+				pending = true
+				i++
+				continue
+			}
+			switch {
+			case n < 0:
+				return nil, fmt.Errorf("negative index %d", n)
+			case n >= limit:
+				return nil, fmt.Errorf("index %d is too far beyond the number of keys", n)
+			}
+			flush()
+			segs = append(segs, segment{kind: _SegIndex, index: n})
+			i = end + 1
+
+		default:
+			buf = append(buf, k[i])
+			pending = true
+			i++
+		}
+	}
+	flush()
+	if err != nil {
+		return nil, err
+	}
+
+	return segs, nil
+}
+
+// isEmptyFlattenKey reports whether k starts with a whole segment of EMPTY_FLATTEN_KEY
+func isEmptyFlattenKey(k string, sep string, bracketIndex bool) bool {
+	if !strings.HasPrefix(k, EMPTY_FLATTEN_KEY) {
+		return false
+	}
+
+	rest := k[len(EMPTY_FLATTEN_KEY):]
+	return rest == "" || strings.HasPrefix(rest, sep) || bracketIndex && rest[0] == '['
+}
@@ -0,0 +1,337 @@
+package jzon
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// GenOptions controls the Go source emitted by `GenerateStructs()`
+type GenOptions struct {
+	// Package is the package clause of the source, it's omitted if empty
+	Package string
+
+	// TagName is the tag naming the key of each field, it's TAG_NAME by default
+	TagName string
+
+	// ValidTags emits `valid:` tags in the grammar of the validator, from the
+	// `minimum`, `maximum` and `enum` constraints of the schema
+	ValidTags bool
+}
+
+func (opts GenOptions) tagName() string {
+	if opts.TagName == "" {
+		return TAG_NAME
+	}
+	return opts.TagName
+}
+
+// commonInitialisms are words written in upper case in Go names
+var commonInitialisms = map[string]bool{
+	"api": true, "ascii": true, "cpu": true, "css": true, "dns": true, "eof": true,
+	"guid": true, "html": true, "http": true, "https": true, "id": true, "ip": true,
+	"json": true, "sql": true, "ssh": true, "tcp": true, "tls": true, "ttl": true,
+	"udp": true, "ui": true, "uid": true, "uri": true, "url": true, "utf8": true,
+	"uuid": true, "xml": true,
+}
+
+// GenerateStructs emits Go source declaring a structure named name, and the
+// structures nested in it, for values accepted by the schema, such as one made
+// by `InferSchema()`. objects with `properties` become structures, and other
+// objects become maps, arrays become slices of their `items`, and a value of
+// several types becomes an interface. keys not listed in `required`, and keys
+// which may be null, become pointers unless the type can be nil already. the
+// structures are tagged for `Serialize()` and `Decode()`, if the schema is not
+// an object, an error will be thrown out
+func GenerateStructs(name string, schema *Jzon, opts GenOptions) (src []byte, err error) {
+	if schema.Type != JzTypeObj {
+		return nil, expectTypeOf(JzTypeObj, schema.Type)
+	}
+
+	g := &generator{opts: opts, names: make(map[string]bool)}
+	if opts.Package != "" {
+		fmt.Fprintf(&g.buf, "package %s\n\n", opts.Package)
+	}
+
+	g.declare(goName(name), schema)
+	for len(g.pending) > 0 {
+		decl := g.pending[0]
+		g.pending = g.pending[1:]
+		g.declareStruct(decl.name, decl.schema)
+	}
+
+	if src, err = format.Source(g.buf.Bytes()); err != nil {
+		return nil, fmt.Errorf("can not format the generated source: %v", err)
+	}
+
+	return src, nil
+}
+
+// GenerateStructsFromSamples infers a schema from the samples by `InferSchema()`,
+// and emits Go source for it as `GenerateStructs()` does
+func GenerateStructsFromSamples(name string, opts GenOptions, samples ...*Jzon) (src []byte, err error) {
+	return GenerateStructs(name, InferSchema(samples...), opts)
+}
+
+type generator struct {
+	opts    GenOptions
+	names   map[string]bool
+	pending []pendingStruct
+	buf     bytes.Buffer
+}
+
+// pendingStruct is a nested structure to be declared
+type pendingStruct struct {
+	name   string
+	schema *Jzon
+}
+
+// unique reserves a type name, and appends a number to it if it's taken
+func (g *generator) unique(name string) string {
+	res := name
+	for i := 2; g.names[res]; i++ {
+		res = name + strconv.Itoa(i)
+	}
+	g.names[res] = true
+
+	return res
+}
+
+// declare declares the root type, as a structure if it's possible
+func (g *generator) declare(name string, schema *Jzon) {
+	if isStructSchema(schema) {
+		g.declareStruct(g.unique(name), schema)
+		return
+	}
+
+	g.names[name] = true
+	typ, _ := g.goType(name+"Item", schema)
+	fmt.Fprintf(&g.buf, "type %s %s\n\n", name, typ)
+}
+
+func (g *generator) declareStruct(name string, schema *Jzon) {
+	props, _ := schema.ValueOf("properties")
+	required := make(map[string]bool)
+	if req, err := schema.ValueOf("required"); err == nil && req.Type == JzTypeArr {
+		for _, k := range req.data.([]*Jzon) {
+			if s, err := k.String(); err == nil {
+				required[s] = true
+			}
+		}
+	}
+
+	fmt.Fprintf(&g.buf, "type %s struct {\n", name)
+
+	fields := make(map[string]bool)
+	for _, k := range props.sortedKeys() {
+		prop := props.data.(map[string]*Jzon)[k]
+
+		field := goName(k)
+		for i := 2; fields[field]; i++ {
+			field = goName(k) + strconv.Itoa(i)
+		}
+		fields[field] = true
+
+		typ, nilable := g.goType(name+field, prop)
+		if !nilable && (!required[k] || hasSchemaType(prop, "null")) {
+			typ = "*" + typ
+		}
+
+		tag := g.opts.tagName() + ":" + strconv.Quote(k)
+		if valid := validTag(prop); g.opts.ValidTags && valid != "" {
+			tag += " " + TAG_VALID + ":" + strconv.Quote(valid)
+		}
+
+		if strings.ContainsRune(tag, '`') {
+			fmt.Fprintf(&g.buf, "\t%s %s %s\n", field, typ, strconv.Quote(tag))
+		} else {
+			fmt.Fprintf(&g.buf, "\t%s %s `%s`\n", field, typ, tag)
+		}
+	}
+
+	fmt.Fprintf(&g.buf, "}\n\n")
+}
+
+// goType returns the Go type of values accepted by the schema, and whether the
+// type can be nil, a structure named name is queued if the schema needs one
+func (g *generator) goType(name string, schema *Jzon) (typ string, nilable bool) {
+	var types []string
+	for _, t := range schemaTypes(schema) {
+		if t != "null" {
+			types = append(types, t)
+		}
+	}
+
+	if len(types) == 0 && isStructSchema(schema) {
+		types = []string{"object"}
+	}
+
+	if len(types) != 1 {
+		return "interface{}", true
+	}
+
+	switch types[0] {
+	case "string":
+		return "string", false
+	case "integer":
+		return "int64", false
+	case "number":
+		return "float64", false
+	case "boolean":
+		return "bool", false
+
+	case "array":
+		items, err := schema.ValueOf("items")
+		if err != nil || items.Type != JzTypeObj {
+			return "[]interface{}", true
+		}
+		elem, _ := g.goType(name+"Item", items)
+		return "[]" + elem, true
+
+	case "object":
+		if !isStructSchema(schema) {
+			return "map[string]interface{}", true
+		}
+		name = g.unique(name)
+		g.pending = append(g.pending, pendingStruct{name, schema})
+		return name, false
+	}
+
+	return "interface{}", true
+}
+
+// isStructSchema reports whether the schema is an object with properties
+func isStructSchema(schema *Jzon) bool {
+	props, err := schema.ValueOf("properties")
+	if err != nil || props.Type != JzTypeObj || len(props.data.(map[string]*Jzon)) == 0 {
+		return false
+	}
+
+	types := schemaTypes(schema)
+	return len(types) == 0 || hasSchemaType(schema, "object")
+}
+
+// schemaTypes returns the `type` of the schema, which is a string or an array of strings
+func schemaTypes(schema *Jzon) (types []string) {
+	t, err := schema.ValueOf("type")
+	if err != nil {
+		return nil
+	}
+
+	if s, err := t.String(); err == nil {
+		return []string{s}
+	}
+
+	if t.Type == JzTypeArr {
+		for _, elem := range t.data.([]*Jzon) {
+			if s, err := elem.String(); err == nil {
+				types = append(types, s)
+			}
+		}
+	}
+
+	return types
+}
+
+func hasSchemaType(schema *Jzon, t string) bool {
+	for _, s := range schemaTypes(schema) {
+		if s == t {
+			return true
+		}
+	}
+	return false
+}
+
+// validTag converts the constraints of the schema to a condition of the validator
+func validTag(schema *Jzon) string {
+	types := schemaTypes(schema)
+	switch {
+	case hasSchemaType(schema, "boolean") && len(types) == 1:
+		return COND_BOOL + "both"
+
+	case hasSchemaType(schema, "integer"):
+		lo, err1 := schema.ValueOf("minimum")
+		hi, err2 := schema.ValueOf("maximum")
+		if enum, err := schema.ValueOf("enum"); err == nil && enum.Type == JzTypeArr {
+			// the range covers the enum candidates, which are all integers or null
+			lo, hi, err1, err2 = nil, nil, nil, nil
+			for _, elem := range enum.data.([]*Jzon) {
+				if elem.Type != JzTypeInt {
+					continue
+				}
+				if lo == nil || Compare(elem, lo) < 0 {
+					lo = elem
+				}
+				if hi == nil || Compare(elem, hi) > 0 {
+					hi = elem
+				}
+			}
+		}
+		// floats of a mixed schema can't pass a range, which only accepts integers
+		if err1 != nil || err2 != nil || lo == nil || hi == nil || lo.Type != JzTypeInt ||
+			hi.Type != JzTypeInt || hi.data.(int64) == math.MaxInt64 || hasSchemaType(schema, "number") {
+			return ""
+		}
+		// the upper bound of a range is exclusive
+		return fmt.Sprintf("%s%d,%d", COND_RANGE, lo.data.(int64), hi.data.(int64)+1)
+
+	case hasSchemaType(schema, "string"):
+		enum, err := schema.ValueOf("enum")
+		if err != nil || enum.Type != JzTypeArr {
+			return ""
+		}
+		var alts []string
+		for _, elem := range enum.data.([]*Jzon) {
+			if s, err := elem.String(); err == nil {
+				alts = append(alts, regexp.QuoteMeta(s))
+			}
+		}
+		if len(alts) == 0 {
+			return ""
+		}
+		return COND_REGEXP + "^(?:" + strings.Join(alts, "|") + ")$"
+	}
+
+	return ""
+}
+
+// goName converts a key to an exported Go identifier, words separated by
+// any character which is not a letter or a digit are capitalized, and the
+// common initialisms, even in plural, are written in upper case, so "id_str"
+// becomes "IDStr" and "urls" becomes "URLs". a name which would not be
+// exported is prefixed with "X"
+func goName(key string) string {
+	var words = strings.FieldsFunc(key, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	var sb strings.Builder
+	for _, w := range words {
+		lower := strings.ToLower(w)
+		if commonInitialisms[lower] {
+			sb.WriteString(strings.ToUpper(w))
+			continue
+		}
+		if stem := strings.TrimSuffix(lower, "s"); stem != lower && commonInitialisms[stem] {
+			sb.WriteString(strings.ToUpper(stem) + "s")
+			continue
+		}
+		r := []rune(w)
+		sb.WriteString(strings.ToUpper(string(r[0])) + string(r[1:]))
+	}
+
+	name := sb.String()
+	switch {
+	case name == "":
+		return "Field"
+	case !unicode.IsUpper([]rune(name)[0]):
+		return "X" + name
+	}
+
+	return name
+}
@@ -0,0 +1,214 @@
+package jzon
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Get decodes the node at the path into a value of type T, see `Decode()`
+// for the rules of decoding. if the node doesn't exist or can't be decoded
+// into T, an error will be thrown out
+//
+//	ports, err := jzon.Get[[]int](jz, "$.server.ports")
+func Get[T any](jz *Jzon, path string) (res T, err error) {
+	v, err := jz.Query(path)
+	if err != nil {
+		return
+	}
+
+	return Decode[T](v)
+}
+
+// Decode decodes the node into a value of type T. booleans, numbers, strings,
+// slices, arrays, maps with string keys, pointers and structures are decoded
+// recursively, fields of structures are matched by tags as `Deserialize()`
+// does, and missing fields are left zero. an integer can be decoded into a
+// float, and a float without fractional part can be decoded into an integer.
+// `*Jzon` and `Jzon` receive the node itself as `NewFromAny()` does, and an
+// interface receives a bool, an int64, a float64, a string, a []Any or a
+// map[string]Any. if any value mismatches, an error will be thrown out
+func Decode[T any](jz *Jzon) (res T, err error) {
+	err = decode(jz, reflect.ValueOf(&res).Elem(), "$")
+	return
+}
+
+// ArrayOf decodes all elements of an array into a slice of T, if it's not an
+// array or any element can't be decoded into T, an error will be thrown out
+func ArrayOf[T any](jz *Jzon) (res []T, err error) {
+	if jz.Type != JzTypeArr {
+		return nil, expectTypeOf(JzTypeArr, jz.Type)
+	}
+
+	err = decode(jz, reflect.ValueOf(&res).Elem(), "$")
+	return
+}
+
+// From builds a node from a value of any type, as `Serialize()` does,
+// if the value can't be serialized, it returns nil
+func From[T any](v T) *Jzon {
+	jz, err := serialize(reflect.ValueOf(&v).Elem())
+	if err != nil {
+		return nil
+	}
+
+	return jz
+}
+
+func decode(jz *Jzon, v reflect.Value, path string) (err error) {
+	t := v.Type()
+	k := t.Kind()
+
+	mismatch := func() error {
+		return fmt.Errorf("at `%s`: can not decode %s into %s", path, typeStrings[jz.Type], t)
+	}
+
+	switch {
+	case t == jzonPtrType:
+		v.Set(reflect.ValueOf(jz.Clone()))
+
+	case t == jzonType:
+		v.Set(reflect.ValueOf(*jz.DeepClone()))
+
+	case k == reflect.Ptr:
+		if jz.IsNull() {
+			v.Set(reflect.Zero(t))
+			return nil
+		}
+		elem := reflect.New(t.Elem())
+		if err = decode(jz, elem.Elem(), path); err != nil {
+			return
+		}
+		v.Set(elem)
+
+	case k == reflect.Interface && t.NumMethod() == 0:
+		if jz.IsNull() {
+			v.Set(reflect.Zero(t))
+			return nil
+		}
+		v.Set(reflect.ValueOf(toAny(jz)))
+
+	case k == reflect.Bool && jz.Type == JzTypeBol:
+		v.SetBool(jz.data.(bool))
+
+	case k == reflect.String && jz.Type == JzTypeStr:
+		v.SetString(jz.data.(string))
+
+	case k >= reflect.Int && k <= reflect.Int64 && isNumber(jz):
+		n, err := toInt(jz)
+		if err != nil || v.OverflowInt(n) {
+			return mismatch()
+		}
+		v.SetInt(n)
+
+	case k >= reflect.Uint && k <= reflect.Uint64 && isNumber(jz):
+		n, err := toInt(jz)
+		if err != nil || n < 0 || v.OverflowUint(uint64(n)) {
+			return mismatch()
+		}
+		v.SetUint(uint64(n))
+
+	case (k == reflect.Float32 || k == reflect.Float64) && isNumber(jz):
+		f := toFloat(jz)
+		if v.OverflowFloat(f) {
+			return mismatch()
+		}
+		v.SetFloat(f)
+
+	case k == reflect.Slice && jz.IsNull():
+		v.Set(reflect.Zero(t))
+
+	case k == reflect.Slice && jz.Type == JzTypeArr:
+		arr := jz.data.([]*Jzon)
+		s := reflect.MakeSlice(t, len(arr), len(arr))
+		for i, elem := range arr {
+			if err = decode(elem, s.Index(i), fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return
+			}
+		}
+		v.Set(s)
+
+	case k == reflect.Array && jz.Type == JzTypeArr:
+		arr := jz.data.([]*Jzon)
+		if len(arr) != v.Len() {
+			return fmt.Errorf("at `%s`: can not decode %d elements into %s", path, len(arr), t)
+		}
+		for i, elem := range arr {
+			if err = decode(elem, v.Index(i), fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return
+			}
+		}
+
+	case k == reflect.Map && jz.IsNull():
+		v.Set(reflect.Zero(t))
+
+	case k == reflect.Map && t.Key().Kind() == reflect.String && jz.Type == JzTypeObj:
+		m := reflect.MakeMap(t)
+		for key, elem := range jz.data.(map[string]*Jzon) {
+			ev := reflect.New(t.Elem()).Elem()
+			if err = decode(elem, ev, path+segment{kind: _SegKey, key: key}.String()); err != nil {
+				return
+			}
+			m.SetMapIndex(reflect.ValueOf(key).Convert(t.Key()), ev)
+		}
+		v.Set(m)
+
+	case k == reflect.Struct && jz.Type == JzTypeObj:
+		m := jz.data.(map[string]*Jzon)
+		for i := 0; i < t.NumField(); i++ {
+			tag := t.Field(i).Tag.Get(TAG_NAME)
+			if tag == "," {
+				tag = t.Field(i).Name
+			}
+
+			elem, ok := m[tag]
+			if tag == "" || tag == "-" || !ok || !t.Field(i).IsExported() {
+				continue
+			}
+
+			if err = decode(elem, v.Field(i), path+segment{kind: _SegKey, key: tag}.String()); err != nil {
+				return
+			}
+		}
+
+	default:
+		return mismatch()
+	}
+
+	return nil
+}
+
+// toInt converts a number to an integer, if it's a float
+// with fractional part, an error will be thrown out
+func toInt(jz *Jzon) (n int64, err error) {
+	if jz.Type == JzTypeInt {
+		return jz.data.(int64), nil
+	}
+
+	return floatToInt(jz.data.(float64))
+}
+
+// toAny converts the node to the natural Go value of it
+func toAny(jz *Jzon) Any {
+	switch jz.Type {
+	case JzTypeArr:
+		arr := jz.data.([]*Jzon)
+		res := make([]Any, 0, len(arr))
+		for _, elem := range arr {
+			res = append(res, toAny(elem))
+		}
+		return res
+
+	case JzTypeObj:
+		m := jz.data.(map[string]*Jzon)
+		res := make(map[string]Any, len(m))
+		for k, elem := range m {
+			res[k] = toAny(elem)
+		}
+		return res
+
+	case JzTypeNul:
+		return nil
+	}
+
+	return jz.data
+}
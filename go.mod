@@ -1,3 +1,3 @@
 module github.com/zuoxinyu/jzon
 
-go 1.12
+go 1.23
@@ -0,0 +1,49 @@
+package jzon
+
+import "iter"
+
+// Entries returns an iterator over keys and values of an object, keys are
+// yielded in ascending order, the same order as `Walk()` and wildcard paths.
+// breaking the loop stops the iteration early. if it's not an object, the
+// iterator yields nothing
+//
+//	for k, v := range jz.Entries() {
+//		...
+//	}
+func (jz *Jzon) Entries() iter.Seq2[string, *Jzon] {
+	return func(yield func(string, *Jzon) bool) {
+		if jz.Type != JzTypeObj {
+			return
+		}
+
+		m := jz.data.(map[string]*Jzon)
+		for _, k := range jz.sortedKeys() {
+			v, ok := m[k]
+			if !ok {
+				// deleted by the loop body
+				continue
+			}
+
+			if !yield(k, v) {
+				return
+			}
+		}
+	}
+}
+
+// Elements returns an iterator over indices and elements of an array,
+// breaking the loop stops the iteration early. if it's not an array,
+// the iterator yields nothing
+func (jz *Jzon) Elements() iter.Seq2[int, *Jzon] {
+	return func(yield func(int, *Jzon) bool) {
+		if jz.Type != JzTypeArr {
+			return
+		}
+
+		for i, v := range jz.data.([]*Jzon) {
+			if !yield(i, v) {
+				return
+			}
+		}
+	}
+}
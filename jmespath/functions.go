@@ -0,0 +1,529 @@
+package jmespath
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/zuoxinyu/jzon"
+)
+
+// argument is an evaluated argument of a function, which is either
+// a value or an expression reference such as `&name`
+type argument struct {
+	value  *jzon.Jzon
+	expref node
+}
+
+// argType is a bit set of types which an argument accepts
+type argType int
+
+const (
+	_ArgNumber argType = 1 << iota
+	_ArgString
+	_ArgBool
+	_ArgArray
+	_ArgObject
+	_ArgNull
+	_ArgExpref
+	_ArgNumbers // array of numbers
+	_ArgStrings // array of strings
+
+	_ArgAny = _ArgNumber | _ArgString | _ArgBool | _ArgArray | _ArgObject | _ArgNull
+)
+
+var argTypeStrings = map[argType]string{
+	_ArgNumber:  "number",
+	_ArgString:  "string",
+	_ArgBool:    "boolean",
+	_ArgArray:   "array",
+	_ArgObject:  "object",
+	_ArgNull:    "null",
+	_ArgExpref:  "expref",
+	_ArgNumbers: "array[number]",
+	_ArgStrings: "array[string]",
+}
+
+func (t argType) String() string {
+	var names []string
+	for bit := _ArgNumber; bit <= _ArgStrings; bit <<= 1 {
+		if t&bit != 0 {
+			names = append(names, argTypeStrings[bit])
+		}
+	}
+	return strings.Join(names, "|")
+}
+
+// function is a built-in function, if variadic is set, the last argument
+// type can be repeated, otherwise the arity must equal to len(args)
+type function struct {
+	args     []argType
+	variadic bool
+	call     func(args []argument) (*jzon.Jzon, error)
+}
+
+var functions map[string]function
+
+func init() {
+	// initialized in init() since some functions call the interpreter
+	functions = map[string]function{
+		"abs":         {[]argType{_ArgNumber}, false, fnAbs},
+		"avg":         {[]argType{_ArgNumbers}, false, fnAvg},
+		"ceil":        {[]argType{_ArgNumber}, false, fnCeil},
+		"contains":    {[]argType{_ArgArray | _ArgString, _ArgAny}, false, fnContains},
+		"ends_with":   {[]argType{_ArgString, _ArgString}, false, fnEndsWith},
+		"floor":       {[]argType{_ArgNumber}, false, fnFloor},
+		"join":        {[]argType{_ArgString, _ArgStrings}, false, fnJoin},
+		"keys":        {[]argType{_ArgObject}, false, fnKeys},
+		"length":      {[]argType{_ArgString | _ArgArray | _ArgObject}, false, fnLength},
+		"map":         {[]argType{_ArgExpref, _ArgArray}, false, fnMap},
+		"max":         {[]argType{_ArgNumbers | _ArgStrings}, false, fnMax},
+		"max_by":      {[]argType{_ArgArray, _ArgExpref}, false, fnMaxBy},
+		"merge":       {[]argType{_ArgObject}, true, fnMerge},
+		"min":         {[]argType{_ArgNumbers | _ArgStrings}, false, fnMin},
+		"min_by":      {[]argType{_ArgArray, _ArgExpref}, false, fnMinBy},
+		"not_null":    {[]argType{_ArgAny}, true, fnNotNull},
+		"reverse":     {[]argType{_ArgArray | _ArgString}, false, fnReverse},
+		"sort":        {[]argType{_ArgNumbers | _ArgStrings}, false, fnSort},
+		"sort_by":     {[]argType{_ArgArray, _ArgExpref}, false, fnSortBy},
+		"starts_with": {[]argType{_ArgString, _ArgString}, false, fnStartsWith},
+		"sum":         {[]argType{_ArgNumbers}, false, fnSum},
+		"to_array":    {[]argType{_ArgAny}, false, fnToArray},
+		"to_number":   {[]argType{_ArgAny}, false, fnToNumber},
+		"to_string":   {[]argType{_ArgAny}, false, fnToString},
+		"type":        {[]argType{_ArgAny}, false, fnType},
+		"values":      {[]argType{_ArgObject}, false, fnValues},
+	}
+}
+
+func (fn function) checkArity(n int) error {
+	if fn.variadic && n < len(fn.args) || !fn.variadic && n != len(fn.args) {
+		return &Error{Kind: ERR_INVALID_ARITY, Pos: -1,
+			Msg: "expect " + strconv.Itoa(len(fn.args)) + " arguments but found " + strconv.Itoa(n)}
+	}
+	return nil
+}
+
+func (fn function) checkTypes(name string, args []argument) error {
+	for i, arg := range args {
+		ex := fn.args[minInt(i, len(fn.args)-1)]
+		if !typeOf(arg).match(ex, arg) {
+			return evalError(ERR_INVALID_TYPE, "argument %d of %s() expects %s but found %s",
+				i+1, name, ex, typeOf(arg))
+		}
+	}
+	return nil
+}
+
+func typeOf(arg argument) argType {
+	if arg.expref != nil {
+		return _ArgExpref
+	}
+
+	switch arg.value.Type {
+	case jzon.JzTypeInt, jzon.JzTypeFlt:
+		return _ArgNumber
+	case jzon.JzTypeStr:
+		return _ArgString
+	case jzon.JzTypeBol:
+		return _ArgBool
+	case jzon.JzTypeArr:
+		return _ArgArray
+	case jzon.JzTypeObj:
+		return _ArgObject
+	}
+
+	return _ArgNull
+}
+
+// match reports whether an argument of type t is accepted by ex, arrays are
+// accepted by typed arrays as long as every element is of the element type
+func (t argType) match(ex argType, arg argument) bool {
+	if t&ex != 0 {
+		return true
+	}
+
+	if t != _ArgArray {
+		return false
+	}
+
+	arr, _ := arg.value.Array()
+	for _, elemType := range []argType{_ArgNumber, _ArgString} {
+		arrType := _ArgNumbers
+		if elemType == _ArgString {
+			arrType = _ArgStrings
+		}
+		if ex&arrType == 0 {
+			continue
+		}
+
+		ok := true
+		for _, elem := range arr {
+			if typeOf(argument{value: elem}) != elemType {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+func number(f float64) *jzon.Jzon {
+	if f == math.Trunc(f) && math.Abs(f) < 1<<53 {
+		return jzon.NewFromAny(int64(f))
+	}
+	return jzon.NewFromAny(f)
+}
+
+func fnAbs(args []argument) (*jzon.Jzon, error) {
+	return number(math.Abs(toFloat(args[0].value))), nil
+}
+
+func fnAvg(args []argument) (*jzon.Jzon, error) {
+	arr, _ := args[0].value.Array()
+	if len(arr) == 0 {
+		return null(), nil
+	}
+
+	sum, _ := fnSum(args)
+	return jzon.NewFromAny(toFloat(sum) / float64(len(arr))), nil
+}
+
+func fnCeil(args []argument) (*jzon.Jzon, error) {
+	return number(math.Ceil(toFloat(args[0].value))), nil
+}
+
+func fnFloor(args []argument) (*jzon.Jzon, error) {
+	return number(math.Floor(toFloat(args[0].value))), nil
+}
+
+func fnContains(args []argument) (*jzon.Jzon, error) {
+	if s, err := args[0].value.String(); err == nil {
+		sub, err := args[1].value.String()
+		return jzon.NewFromAny(err == nil && strings.Contains(s, sub)), nil
+	}
+
+	arr, _ := args[0].value.Array()
+	for _, elem := range arr {
+		if equal(elem, args[1].value) {
+			return jzon.NewFromAny(true), nil
+		}
+	}
+
+	return jzon.NewFromAny(false), nil
+}
+
+func fnEndsWith(args []argument) (*jzon.Jzon, error) {
+	s, _ := args[0].value.String()
+	suffix, _ := args[1].value.String()
+	return jzon.NewFromAny(strings.HasSuffix(s, suffix)), nil
+}
+
+func fnStartsWith(args []argument) (*jzon.Jzon, error) {
+	s, _ := args[0].value.String()
+	prefix, _ := args[1].value.String()
+	return jzon.NewFromAny(strings.HasPrefix(s, prefix)), nil
+}
+
+func fnJoin(args []argument) (*jzon.Jzon, error) {
+	glue, _ := args[0].value.String()
+	arr, _ := args[1].value.Array()
+
+	var ss = make([]string, 0, len(arr))
+	for _, elem := range arr {
+		s, _ := elem.String()
+		ss = append(ss, s)
+	}
+
+	return jzon.NewFromAny(strings.Join(ss, glue)), nil
+}
+
+func fnKeys(args []argument) (*jzon.Jzon, error) {
+	keys, _ := args[0].value.Keys()
+	sort.Strings(keys)
+
+	var res = make([]*jzon.Jzon, 0, len(keys))
+	for _, k := range keys {
+		res = append(res, jzon.NewFromAny(k))
+	}
+
+	return jzon.NewFromAny(res), nil
+}
+
+func fnValues(args []argument) (*jzon.Jzon, error) {
+	return jzon.NewFromAny(objectValues(args[0].value)), nil
+}
+
+func fnLength(args []argument) (*jzon.Jzon, error) {
+	if s, err := args[0].value.String(); err == nil {
+		return jzon.NewFromAny(utf8.RuneCountInString(s)), nil
+	}
+
+	l, _ := args[0].value.Length()
+	return jzon.NewFromAny(l), nil
+}
+
+func fnMap(args []argument) (*jzon.Jzon, error) {
+	arr, _ := args[1].value.Array()
+
+	var res = make([]*jzon.Jzon, 0, len(arr))
+	for _, elem := range arr {
+		v, err := args[0].expref.eval(elem)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, v)
+	}
+
+	return jzon.NewFromAny(res), nil
+}
+
+// extreme returns the element of arr whose key is the greatest when sign
+// is 1 or the least when sign is -1, keys must be all numbers or all strings
+func extreme(arr []*jzon.Jzon, key func(*jzon.Jzon) (*jzon.Jzon, error), sign int) (*jzon.Jzon, error) {
+	keys, err := sortKeys(arr, key)
+	if err != nil || len(arr) == 0 {
+		return null(), err
+	}
+
+	best := 0
+	for i := 1; i < len(arr); i++ {
+		if compare(keys[i], keys[best])*sign > 0 {
+			best = i
+		}
+	}
+
+	return arr[best], nil
+}
+
+// sortKeys evaluates the key of each element, and checks that all
+// keys are numbers, or all keys are strings
+func sortKeys(arr []*jzon.Jzon, key func(*jzon.Jzon) (*jzon.Jzon, error)) (keys []*jzon.Jzon, err error) {
+	var kind argType
+	for _, elem := range arr {
+		k, err := key(elem)
+		if err != nil {
+			return nil, err
+		}
+
+		t := typeOf(argument{value: k})
+		if t != _ArgNumber && t != _ArgString || kind != 0 && t != kind {
+			return nil, evalError(ERR_INVALID_TYPE, "expect all keys to be numbers or strings, but found %s", t)
+		}
+		kind = t
+		keys = append(keys, k)
+	}
+
+	return keys, nil
+}
+
+func compare(a, b *jzon.Jzon) int {
+	if isNumber(a) {
+		x, y := toFloat(a), toFloat(b)
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+		return 0
+	}
+
+	x, _ := a.String()
+	y, _ := b.String()
+	return strings.Compare(x, y)
+}
+
+func identity(v *jzon.Jzon) (*jzon.Jzon, error) {
+	return v, nil
+}
+
+func fnMax(args []argument) (*jzon.Jzon, error) {
+	arr, _ := args[0].value.Array()
+	return extreme(arr, identity, 1)
+}
+
+func fnMin(args []argument) (*jzon.Jzon, error) {
+	arr, _ := args[0].value.Array()
+	return extreme(arr, identity, -1)
+}
+
+func fnMaxBy(args []argument) (*jzon.Jzon, error) {
+	arr, _ := args[0].value.Array()
+	return extreme(arr, args[1].expref.eval, 1)
+}
+
+func fnMinBy(args []argument) (*jzon.Jzon, error) {
+	arr, _ := args[0].value.Array()
+	return extreme(arr, args[1].expref.eval, -1)
+}
+
+func fnMerge(args []argument) (*jzon.Jzon, error) {
+	merged := jzon.New(jzon.JzTypeObj)
+	for _, arg := range args {
+		m, _ := arg.value.Object()
+		for k, v := range m {
+			merged.Insert(k, v)
+		}
+	}
+
+	return merged, nil
+}
+
+func fnNotNull(args []argument) (*jzon.Jzon, error) {
+	for _, arg := range args {
+		if !arg.value.IsNull() {
+			return arg.value, nil
+		}
+	}
+
+	return null(), nil
+}
+
+func fnReverse(args []argument) (*jzon.Jzon, error) {
+	if s, err := args[0].value.String(); err == nil {
+		runes := []rune(s)
+		for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+			runes[i], runes[j] = runes[j], runes[i]
+		}
+		return jzon.NewFromAny(string(runes)), nil
+	}
+
+	arr, _ := args[0].value.Array()
+	reversed := make([]*jzon.Jzon, len(arr))
+	for i, elem := range arr {
+		reversed[len(arr)-1-i] = elem
+	}
+
+	return jzon.NewFromAny(reversed), nil
+}
+
+func sortBy(arr []*jzon.Jzon, key func(*jzon.Jzon) (*jzon.Jzon, error)) (*jzon.Jzon, error) {
+	keys, err := sortKeys(arr, key)
+	if err != nil {
+		return nil, err
+	}
+
+	indices := make([]int, len(arr))
+	for i := range indices {
+		indices[i] = i
+	}
+	sort.SliceStable(indices, func(i, j int) bool {
+		return compare(keys[indices[i]], keys[indices[j]]) < 0
+	})
+
+	sorted := make([]*jzon.Jzon, 0, len(arr))
+	for _, i := range indices {
+		sorted = append(sorted, arr[i])
+	}
+
+	return jzon.NewFromAny(sorted), nil
+}
+
+func fnSort(args []argument) (*jzon.Jzon, error) {
+	arr, _ := args[0].value.Array()
+	return sortBy(arr, identity)
+}
+
+func fnSortBy(args []argument) (*jzon.Jzon, error) {
+	arr, _ := args[0].value.Array()
+	return sortBy(arr, args[1].expref.eval)
+}
+
+func fnSum(args []argument) (*jzon.Jzon, error) {
+	arr, _ := args[0].value.Array()
+
+	var isInt = true
+	var n int64
+	var f float64
+	for _, elem := range arr {
+		if i, err := elem.Integer(); err == nil {
+			n += i
+		} else {
+			isInt = false
+		}
+		f += toFloat(elem)
+	}
+
+	if isInt {
+		return jzon.NewFromAny(n), nil
+	}
+
+	return jzon.NewFromAny(f), nil
+}
+
+func fnToArray(args []argument) (*jzon.Jzon, error) {
+	if args[0].value.Type == jzon.JzTypeArr {
+		return args[0].value, nil
+	}
+
+	return jzon.NewFromAny([]*jzon.Jzon{args[0].value}), nil
+}
+
+func fnToNumber(args []argument) (*jzon.Jzon, error) {
+	v := args[0].value
+	if isNumber(v) {
+		return v, nil
+	}
+
+	if s, err := v.String(); err == nil {
+		if n, err := jzon.Parse([]byte(s)); err == nil && isNumber(n) {
+			return n, nil
+		}
+	}
+
+	return null(), nil
+}
+
+func fnToString(args []argument) (*jzon.Jzon, error) {
+	if args[0].value.Type == jzon.JzTypeStr {
+		return args[0].value, nil
+	}
+
+	return jzon.NewFromAny(encode(args[0].value)), nil
+}
+
+// encode generates compact JSON text as `Compact()` does, except that numbers
+// are formatted in the shortest form, and keys of objects are sorted
+func encode(v *jzon.Jzon) string {
+	switch v.Type {
+	case jzon.JzTypeFlt:
+		f, _ := v.Float()
+		return strconv.FormatFloat(f, 'g', -1, 64)
+
+	case jzon.JzTypeArr:
+		arr, _ := v.Array()
+		var ss = make([]string, 0, len(arr))
+		for _, elem := range arr {
+			ss = append(ss, encode(elem))
+		}
+		return "[" + strings.Join(ss, ",") + "]"
+
+	case jzon.JzTypeObj:
+		m, _ := v.Object()
+		keys, _ := v.Keys()
+		sort.Strings(keys)
+		var ss = make([]string, 0, len(keys))
+		for _, k := range keys {
+			ss = append(ss, jzon.NewFromAny(k).Compact()+":"+encode(m[k]))
+		}
+		return "{" + strings.Join(ss, ",") + "}"
+	}
+
+	return v.Compact()
+}
+
+func fnType(args []argument) (*jzon.Jzon, error) {
+	t := typeOf(args[0])
+	if t == _ArgBool {
+		return jzon.NewFromAny("boolean"), nil
+	}
+
+	return jzon.NewFromAny(argTypeStrings[t]), nil
+}
@@ -0,0 +1,436 @@
+package jmespath
+
+import (
+	"sort"
+
+	"github.com/zuoxinyu/jzon"
+)
+
+// node is a node of the abstract syntax tree of an expression
+type node interface {
+	eval(data *jzon.Jzon) (*jzon.Jzon, error)
+}
+
+type (
+	currentNode  struct{}
+	literalNode  struct{ value *jzon.Jzon }
+	fieldNode    struct{ name string }
+	indexNode    struct{ index int }
+	sliceNode    struct{ start, stop, step *int }
+	flattenNode  struct{ child node }
+	notNode      struct{ child node }
+	exprefNode   struct{ child node }
+	subexprNode  struct{ left, right node }
+	pipeNode     struct{ left, right node }
+	orNode       struct{ left, right node }
+	andNode      struct{ left, right node }
+	listNode     struct{ items []node }
+	functionNode struct {
+		name string
+		fn   function
+		args []node
+	}
+	hashNode struct {
+		keys   []string
+		values []node
+	}
+	indexExprNode       struct{ left, right node }
+	projectionNode      struct{ left, right node }
+	valueProjectionNode struct{ left, right node }
+	filterNode          struct{ left, right, cond node }
+	comparatorNode      struct {
+		op          tokKind
+		left, right node
+	}
+)
+
+func null() *jzon.Jzon {
+	return jzon.New(jzon.JzTypeNul)
+}
+
+func (currentNode) eval(data *jzon.Jzon) (*jzon.Jzon, error) {
+	return data, nil
+}
+
+func (n literalNode) eval(data *jzon.Jzon) (*jzon.Jzon, error) {
+	return n.value, nil
+}
+
+func (n fieldNode) eval(data *jzon.Jzon) (*jzon.Jzon, error) {
+	if v, err := data.ValueOf(n.name); err == nil {
+		return v, nil
+	}
+	return null(), nil
+}
+
+func (n indexNode) eval(data *jzon.Jzon) (*jzon.Jzon, error) {
+	arr, err := data.Array()
+	if err != nil {
+		return null(), nil
+	}
+
+	i := n.index
+	if i < 0 {
+		i += len(arr)
+	}
+	if i < 0 || i >= len(arr) {
+		return null(), nil
+	}
+
+	return arr[i], nil
+}
+
+func (n sliceNode) eval(data *jzon.Jzon) (*jzon.Jzon, error) {
+	arr, err := data.Array()
+	if err != nil {
+		return null(), nil
+	}
+
+	var step = 1
+	if n.step != nil {
+		step = *n.step
+	}
+
+	length := len(arr)
+	bound := func(b *int, def int) int {
+		if b == nil {
+			return def
+		}
+		v := *b
+		switch {
+		case v < 0 && step < 0:
+			v = maxInt(v+length, -1)
+		case v < 0:
+			v = maxInt(v+length, 0)
+		case step < 0:
+			v = minInt(v, length-1)
+		default:
+			v = minInt(v, length)
+		}
+		return v
+	}
+
+	var sliced = make([]*jzon.Jzon, 0)
+	if step > 0 {
+		for i := bound(n.start, 0); i < bound(n.stop, length); i += step {
+			sliced = append(sliced, arr[i])
+		}
+	} else {
+		for i := bound(n.start, length-1); i > bound(n.stop, -1); i += step {
+			sliced = append(sliced, arr[i])
+		}
+	}
+
+	return jzon.NewFromAny(sliced), nil
+}
+
+func (n flattenNode) eval(data *jzon.Jzon) (*jzon.Jzon, error) {
+	v, err := n.child.eval(data)
+	if err != nil {
+		return nil, err
+	}
+
+	arr, err := v.Array()
+	if err != nil {
+		return null(), nil
+	}
+
+	var flattened = make([]*jzon.Jzon, 0, len(arr))
+	for _, elem := range arr {
+		if inner, err := elem.Array(); err == nil {
+			flattened = append(flattened, inner...)
+		} else {
+			flattened = append(flattened, elem)
+		}
+	}
+
+	return jzon.NewFromAny(flattened), nil
+}
+
+func (n notNode) eval(data *jzon.Jzon) (*jzon.Jzon, error) {
+	v, err := n.child.eval(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return jzon.NewFromAny(!isTruthy(v)), nil
+}
+
+func (n exprefNode) eval(data *jzon.Jzon) (*jzon.Jzon, error) {
+	return nil, evalError(ERR_INVALID_TYPE, "expression reference can only be an argument of functions")
+}
+
+func (n subexprNode) eval(data *jzon.Jzon) (*jzon.Jzon, error) {
+	left, err := n.left.eval(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return n.right.eval(left)
+}
+
+func (n pipeNode) eval(data *jzon.Jzon) (*jzon.Jzon, error) {
+	left, err := n.left.eval(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return n.right.eval(left)
+}
+
+func (n indexExprNode) eval(data *jzon.Jzon) (*jzon.Jzon, error) {
+	left, err := n.left.eval(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return n.right.eval(left)
+}
+
+func (n orNode) eval(data *jzon.Jzon) (*jzon.Jzon, error) {
+	left, err := n.left.eval(data)
+	if err != nil || isTruthy(left) {
+		return left, err
+	}
+
+	return n.right.eval(data)
+}
+
+func (n andNode) eval(data *jzon.Jzon) (*jzon.Jzon, error) {
+	left, err := n.left.eval(data)
+	if err != nil || !isTruthy(left) {
+		return left, err
+	}
+
+	return n.right.eval(data)
+}
+
+func (n listNode) eval(data *jzon.Jzon) (*jzon.Jzon, error) {
+	if data.IsNull() {
+		return data, nil
+	}
+
+	var list = make([]*jzon.Jzon, 0, len(n.items))
+	for _, item := range n.items {
+		v, err := item.eval(data)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, v)
+	}
+
+	return jzon.NewFromAny(list), nil
+}
+
+func (n hashNode) eval(data *jzon.Jzon) (*jzon.Jzon, error) {
+	if data.IsNull() {
+		return data, nil
+	}
+
+	var hash = jzon.New(jzon.JzTypeObj)
+	for i, k := range n.keys {
+		v, err := n.values[i].eval(data)
+		if err != nil {
+			return nil, err
+		}
+		hash.Insert(k, v)
+	}
+
+	return hash, nil
+}
+
+// project evaluates right on each element, and collects non-null results
+func project(elems []*jzon.Jzon, right node) (*jzon.Jzon, error) {
+	var collected = make([]*jzon.Jzon, 0, len(elems))
+	for _, elem := range elems {
+		v, err := right.eval(elem)
+		if err != nil {
+			return nil, err
+		}
+		if !v.IsNull() {
+			collected = append(collected, v)
+		}
+	}
+
+	return jzon.NewFromAny(collected), nil
+}
+
+func (n projectionNode) eval(data *jzon.Jzon) (*jzon.Jzon, error) {
+	left, err := n.left.eval(data)
+	if err != nil {
+		return nil, err
+	}
+
+	arr, err := left.Array()
+	if err != nil {
+		return null(), nil
+	}
+
+	return project(arr, n.right)
+}
+
+func (n valueProjectionNode) eval(data *jzon.Jzon) (*jzon.Jzon, error) {
+	left, err := n.left.eval(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if left.Type != jzon.JzTypeObj {
+		return null(), nil
+	}
+
+	return project(objectValues(left), n.right)
+}
+
+func (n filterNode) eval(data *jzon.Jzon) (*jzon.Jzon, error) {
+	left, err := n.left.eval(data)
+	if err != nil {
+		return nil, err
+	}
+
+	arr, err := left.Array()
+	if err != nil {
+		return null(), nil
+	}
+
+	var matched []*jzon.Jzon
+	for _, elem := range arr {
+		cond, err := n.cond.eval(elem)
+		if err != nil {
+			return nil, err
+		}
+		if isTruthy(cond) {
+			matched = append(matched, elem)
+		}
+	}
+
+	return project(matched, n.right)
+}
+
+func (n comparatorNode) eval(data *jzon.Jzon) (*jzon.Jzon, error) {
+	left, err := n.left.eval(data)
+	if err != nil {
+		return nil, err
+	}
+
+	right, err := n.right.eval(data)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.op {
+	case _TokEQ:
+		return jzon.NewFromAny(equal(left, right)), nil
+	case _TokNE:
+		return jzon.NewFromAny(!equal(left, right)), nil
+	}
+
+	if !isNumber(left) || !isNumber(right) {
+		return null(), nil
+	}
+
+	a, b := toFloat(left), toFloat(right)
+	switch n.op {
+	case _TokLT:
+		return jzon.NewFromAny(a < b), nil
+	case _TokLTE:
+		return jzon.NewFromAny(a <= b), nil
+	case _TokGT:
+		return jzon.NewFromAny(a > b), nil
+	}
+
+	return jzon.NewFromAny(a >= b), nil
+}
+
+func (n functionNode) eval(data *jzon.Jzon) (*jzon.Jzon, error) {
+	var args = make([]argument, 0, len(n.args))
+	for _, arg := range n.args {
+		if ref, ok := arg.(exprefNode); ok {
+			args = append(args, argument{expref: ref.child})
+			continue
+		}
+
+		v, err := arg.eval(data)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, argument{value: v})
+	}
+
+	if err := n.fn.checkTypes(n.name, args); err != nil {
+		return nil, err
+	}
+
+	return n.fn.call(args)
+}
+
+// isTruthy reports whether the value is true, where false, null,
+// empty strings, empty arrays and empty objects are false values
+func isTruthy(v *jzon.Jzon) bool {
+	switch v.Type {
+	case jzon.JzTypeNul:
+		return false
+	case jzon.JzTypeBol:
+		b, _ := v.Bool()
+		return b
+	case jzon.JzTypeStr:
+		s, _ := v.String()
+		return s != ""
+	case jzon.JzTypeArr, jzon.JzTypeObj:
+		l, _ := v.Length()
+		return l > 0
+	}
+
+	return true
+}
+
+func isNumber(v *jzon.Jzon) bool {
+	return v.Type == jzon.JzTypeInt || v.Type == jzon.JzTypeFlt
+}
+
+func toFloat(v *jzon.Jzon) float64 {
+	if n, err := v.Integer(); err == nil {
+		return float64(n)
+	}
+
+	f, _ := v.Float()
+	return f
+}
+
+// objectValues returns values of an object in the order of sorted keys,
+// for other types it returns nil
+func objectValues(v *jzon.Jzon) (values []*jzon.Jzon) {
+	m, err := v.Object()
+	if err != nil {
+		return nil
+	}
+
+	keys, _ := v.Keys()
+	sort.Strings(keys)
+
+	values = make([]*jzon.Jzon, 0, len(keys))
+	for _, k := range keys {
+		values = append(values, m[k])
+	}
+
+	return values
+}
+
+// equal reports whether a and b are deeply equal, numbers are compared by values
+func equal(a, b *jzon.Jzon) bool {
+	return a.EqualWith(b, jzon.EqualOptions{NumericEquivalence: true})
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
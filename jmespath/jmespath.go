@@ -0,0 +1,101 @@
+// Package jmespath implements JMESPath (https://jmespath.org), a query language
+// for JSON, on top of jzon. expressions are evaluated directly on `*jzon.Jzon`
+// nodes, so the results share nodes with the data being searched, and nothing
+// is converted to or from `interface{}` values.
+package jmespath
+
+import (
+	"fmt"
+
+	"github.com/zuoxinyu/jzon"
+)
+
+// Error kinds, which are the same as the error types of the compliance tests
+const (
+	ERR_SYNTAX           = "syntax"
+	ERR_INVALID_TYPE     = "invalid-type"
+	ERR_INVALID_VALUE    = "invalid-value"
+	ERR_INVALID_ARITY    = "invalid-arity"
+	ERR_UNKNOWN_FUNCTION = "unknown-function"
+)
+
+// Error is the error thrown out at compiling or evaluating an expression
+type Error struct {
+	Kind string
+	Pos  int // the offset in the expression, -1 for errors at evaluating
+	Msg  string
+}
+
+func (e *Error) Error() string {
+	if e.Pos < 0 {
+		return fmt.Sprintf("%s error: %s", e.Kind, e.Msg)
+	}
+	return fmt.Sprintf("%s error at %d: %s", e.Kind, e.Pos, e.Msg)
+}
+
+func syntaxError(pos int, format string, args ...interface{}) error {
+	return &Error{Kind: ERR_SYNTAX, Pos: pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+func evalError(kind string, format string, args ...interface{}) error {
+	return &Error{Kind: kind, Pos: -1, Msg: fmt.Sprintf(format, args...)}
+}
+
+// Expression is a compiled JMESPath expression, it's safe for concurrent use
+type Expression struct {
+	source string
+	root   node
+}
+
+// Compile parses the expression, if it's malformed, an error will be thrown out
+func Compile(expression string) (e *Expression, err error) {
+	tokens, err := lex(expression)
+	if err != nil {
+		return
+	}
+
+	p := &parser{tokens: tokens}
+	root, err := p.parseExpression(0)
+	if err != nil {
+		return
+	}
+
+	if tok := p.peek(); tok.kind != _TokEOF {
+		return nil, syntaxError(tok.pos, "unexpected token `%s`", tok.text)
+	}
+
+	return &Expression{source: expression, root: root}, nil
+}
+
+// MustCompile performs as `Compile()`, but it panics if the expression is malformed
+func MustCompile(expression string) *Expression {
+	e, err := Compile(expression)
+	if err != nil {
+		panic(err)
+	}
+	return e
+}
+
+// String returns the source of the expression
+func (e *Expression) String() string {
+	return e.source
+}
+
+// Search evaluates the expression against data, a missing result is a null node
+func (e *Expression) Search(data *jzon.Jzon) (res *jzon.Jzon, err error) {
+	if data == nil {
+		data = jzon.New(jzon.JzTypeNul)
+	}
+
+	return e.root.eval(data)
+}
+
+// Search compiles the expression and evaluates it against data
+func Search(expression string, data *jzon.Jzon) (res *jzon.Jzon, err error) {
+	e, err := Compile(expression)
+	if err != nil {
+		return
+	}
+
+	return e.Search(data)
+}
@@ -0,0 +1,101 @@
+package jmespath
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/zuoxinyu/jzon"
+)
+
+func TestCompliance(t *testing.T) {
+	files, err := filepath.Glob("../data/jmespath/*.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(files) == 0 {
+		t.Fatal("expect compliance test files in data/jmespath")
+	}
+
+	for _, file := range files {
+		content, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		suites, err := jzon.Parse(content)
+		if err != nil {
+			t.Fatalf("fail on file %s: %v", file, err)
+		}
+
+		arr, _ := suites.Array()
+		for _, suite := range arr {
+			given, _ := suite.ValueOf("given")
+			cases, _ := suite.ValueOf("cases")
+			runCases(t, filepath.Base(file), given, cases)
+		}
+	}
+}
+
+func runCases(t *testing.T, file string, given *jzon.Jzon, cases *jzon.Jzon) {
+	arr, _ := cases.Array()
+	for _, c := range arr {
+		expression, _ := c.ValueOf("expression")
+		expr, _ := expression.String()
+
+		res, err := Search(expr, given)
+
+		if has, _ := c.Has("error"); has {
+			if err == nil {
+				t.Errorf("%s: expect error for `%s`, but got %s", file, expr, res.Compact())
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: `%s`: %v", file, expr, err)
+			continue
+		}
+
+		ex, _ := c.ValueOf("result")
+		if !equal(ex, res) {
+			t.Errorf("%s: `%s`: expect %s, but got %s", file, expr, ex.Compact(), res.Compact())
+		}
+	}
+}
+
+func TestSearch(t *testing.T) {
+	data, err := jzon.Parse([]byte(`{"people": [
+		{"name": "a", "age": 20},
+		{"name": "b", "age": 40},
+		{"name": "c", "age": 35}
+	]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := Search("people[?age > `30`].name | sort(@)", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if compact := res.Compact(); compact != `["b","c"]` {
+		t.Errorf("expect [\"b\",\"c\"], but got %s", compact)
+	}
+
+	e := MustCompile("max_by(people, &age).name")
+	if res, _ = e.Search(data); res.Compact() != `"b"` {
+		t.Errorf("expect \"b\", but got %s", res.Compact())
+	}
+
+	if _, err = Search("people[?", data); err == nil {
+		t.Errorf("expect syntax error")
+	} else if jerr, ok := err.(*Error); !ok || jerr.Kind != ERR_SYNTAX {
+		t.Errorf("expect syntax error, but got %v", err)
+	}
+
+	if _, err = Search("unknown(people)", data); err == nil {
+		t.Errorf("expect unknown-function error")
+	}
+}
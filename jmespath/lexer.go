@@ -0,0 +1,278 @@
+package jmespath
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/zuoxinyu/jzon"
+)
+
+// tokKind indicates the kind of a token of JMESPath expressions
+type tokKind int
+
+const (
+	_TokEOF      tokKind = iota
+	_TokIdent            // foo
+	_TokQuoted           // "foo"
+	_TokLiteral          // `{"a": 1}`
+	_TokRawStr           // 'foo'
+	_TokNumber           // -12
+	_TokDot              // .
+	_TokStar             // *
+	_TokLBracket         // [
+	_TokRBracket         // ]
+	_TokFilter           // [?
+	_TokFlatten          // []
+	_TokLBrace           // {
+	_TokRBrace           // }
+	_TokLParen           // (
+	_TokRParen           // )
+	_TokComma            // ,
+	_TokColon            // :
+	_TokPipe             // |
+	_TokOr               // ||
+	_TokAnd              // &&
+	_TokNot              // !
+	_TokEQ               // ==
+	_TokNE               // !=
+	_TokLT               // <
+	_TokLTE              // <=
+	_TokGT               // >
+	_TokGTE              // >=
+	_TokCurrent          // @
+	_TokExpref           // &
+)
+
+// bindingPowers decides how tightly an operator binds to its left expression
+var bindingPowers = map[tokKind]int{
+	_TokPipe:     1,
+	_TokOr:       2,
+	_TokAnd:      3,
+	_TokEQ:       5,
+	_TokNE:       5,
+	_TokLT:       5,
+	_TokLTE:      5,
+	_TokGT:       5,
+	_TokGTE:      5,
+	_TokFlatten:  9,
+	_TokStar:     20,
+	_TokFilter:   21,
+	_TokDot:      40,
+	_TokNot:      45,
+	_TokLBrace:   50,
+	_TokLBracket: 55,
+	_TokLParen:   60,
+}
+
+// projectionStop is the binding power under which a projection stops
+const projectionStop = 10
+
+type token struct {
+	kind  tokKind
+	text  string
+	pos   int
+	value *jzon.Jzon // for literals, raw strings and quoted identifiers
+	num   int        // for numbers
+}
+
+var simpleTokens = map[byte]tokKind{
+	'.': _TokDot,
+	'*': _TokStar,
+	']': _TokRBracket,
+	'{': _TokLBrace,
+	'}': _TokRBrace,
+	'(': _TokLParen,
+	')': _TokRParen,
+	',': _TokComma,
+	':': _TokColon,
+	'@': _TokCurrent,
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+func isIdentChar(c byte) bool {
+	return isIdentStart(c) || '0' <= c && c <= '9'
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+func expectChar(ex string, src string, i int) error {
+	if i >= len(src) {
+		return syntaxError(i, "expect %s but found end of expression", ex)
+	}
+	return syntaxError(i, "expect %s but found '%c'", ex, src[i])
+}
+
+func lex(src string) (tokens []token, err error) {
+	var i = 0
+	for i < len(src) {
+		c := src[i]
+		start := i
+
+		if kind, ok := simpleTokens[c]; ok {
+			i++
+			tokens = append(tokens, token{kind: kind, text: src[start:i], pos: start})
+			continue
+		}
+
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+			continue
+
+		case isIdentStart(c):
+			for i < len(src) && isIdentChar(src[i]) {
+				i++
+			}
+			tokens = append(tokens, token{kind: _TokIdent, text: src[start:i], pos: start})
+
+		case isDigit(c) || c == '-':
+			i++
+			for i < len(src) && isDigit(src[i]) {
+				i++
+			}
+			if src[start:i] == "-" {
+				return nil, expectChar("a digit after '-'", src, i)
+			}
+			var n int
+			fmt.Sscanf(src[start:i], "%d", &n)
+			tokens = append(tokens, token{kind: _TokNumber, text: src[start:i], pos: start, num: n})
+
+		case c == '[':
+			i++
+			kind := _TokLBracket
+			if i < len(src) && src[i] == '?' {
+				kind = _TokFilter
+				i++
+			} else if i < len(src) && src[i] == ']' {
+				kind = _TokFlatten
+				i++
+			}
+			tokens = append(tokens, token{kind: kind, text: src[start:i], pos: start})
+
+		case c == '"':
+			var tok token
+			if tok, i, err = lexQuoted(src, i); err != nil {
+				return
+			}
+			tokens = append(tokens, tok)
+
+		case c == '\'':
+			var tok token
+			if tok, i, err = lexRawString(src, i); err != nil {
+				return
+			}
+			tokens = append(tokens, tok)
+
+		case c == '`':
+			var tok token
+			if tok, i, err = lexLiteral(src, i); err != nil {
+				return
+			}
+			tokens = append(tokens, tok)
+
+		default:
+			var kind tokKind
+			if kind, i, err = lexOperator(src, i); err != nil {
+				return
+			}
+			tokens = append(tokens, token{kind: kind, text: src[start:i], pos: start})
+		}
+	}
+
+	return append(tokens, token{kind: _TokEOF, pos: len(src)}), nil
+}
+
+func lexOperator(src string, i int) (kind tokKind, end int, err error) {
+	var next byte
+	if i+1 < len(src) {
+		next = src[i+1]
+	}
+
+	switch {
+	case src[i] == '|' && next == '|':
+		return _TokOr, i + 2, nil
+	case src[i] == '|':
+		return _TokPipe, i + 1, nil
+	case src[i] == '&' && next == '&':
+		return _TokAnd, i + 2, nil
+	case src[i] == '&':
+		return _TokExpref, i + 1, nil
+	case src[i] == '!' && next == '=':
+		return _TokNE, i + 2, nil
+	case src[i] == '!':
+		return _TokNot, i + 1, nil
+	case src[i] == '=' && next == '=':
+		return _TokEQ, i + 2, nil
+	case src[i] == '<' && next == '=':
+		return _TokLTE, i + 2, nil
+	case src[i] == '<':
+		return _TokLT, i + 1, nil
+	case src[i] == '>' && next == '=':
+		return _TokGTE, i + 2, nil
+	case src[i] == '>':
+		return _TokGT, i + 1, nil
+	}
+
+	return _TokEOF, i, syntaxError(i, "unknown character '%c'", src[i])
+}
+
+// scanDelimited finds the closing delimiter, skipping those escaped by a backslash
+func scanDelimited(src string, i int, delim byte) (end int, err error) {
+	for j := i + 1; j < len(src); j++ {
+		switch src[j] {
+		case '\\':
+			j++
+		case delim:
+			return j, nil
+		}
+	}
+
+	return len(src), syntaxError(i, "unclosed delimiter %c", delim)
+}
+
+func lexQuoted(src string, i int) (tok token, end int, err error) {
+	if end, err = scanDelimited(src, i, '"'); err != nil {
+		return
+	}
+
+	text := src[i : end+1]
+	value, err := jzon.Parse([]byte(text))
+	if err != nil {
+		return tok, end, syntaxError(i, "invalid quoted identifier %s: %v", text, err)
+	}
+
+	return token{kind: _TokQuoted, text: text, pos: i, value: value}, end + 1, nil
+}
+
+func lexRawString(src string, i int) (tok token, end int, err error) {
+	if end, err = scanDelimited(src, i, '\''); err != nil {
+		return
+	}
+
+	text := src[i : end+1]
+	raw := strings.Replace(src[i+1:end], "\\'", "'", -1)
+	return token{kind: _TokRawStr, text: text, pos: i, value: jzon.NewFromAny(raw)}, end + 1, nil
+}
+
+func lexLiteral(src string, i int) (tok token, end int, err error) {
+	if end, err = scanDelimited(src, i, '`'); err != nil {
+		return
+	}
+
+	text := src[i : end+1]
+	raw := strings.TrimSpace(strings.Replace(src[i+1:end], "\\`", "`", -1))
+	value, err := jzon.Parse([]byte(raw))
+	if err != nil {
+		// the deprecated form where a string literal isn't quoted
+		if value, err = jzon.Parse([]byte(`"` + raw + `"`)); err != nil {
+			return tok, end, syntaxError(i, "invalid JSON literal %s", text)
+		}
+	}
+
+	return token{kind: _TokLiteral, text: text, pos: i, value: value}, end + 1, nil
+}
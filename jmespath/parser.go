@@ -0,0 +1,413 @@
+package jmespath
+
+// parser is a top down operator precedence parser, each token has a binding
+// power, `nud()` parses a token at the beginning of an expression, and `led()`
+// parses a token following its left expression
+type parser struct {
+	tokens []token
+	curr   int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.curr]
+}
+
+func (p *parser) lookahead(n int) tokKind {
+	if p.curr+n >= len(p.tokens) {
+		return _TokEOF
+	}
+	return p.tokens[p.curr+n].kind
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.curr]
+	if tok.kind != _TokEOF {
+		p.curr++
+	}
+	return tok
+}
+
+func (p *parser) expect(kind tokKind, ex string) error {
+	tok := p.next()
+	if tok.kind != kind {
+		return unexpected(tok, ex)
+	}
+	return nil
+}
+
+func unexpected(tok token, ex string) error {
+	if tok.kind == _TokEOF {
+		return syntaxError(tok.pos, "expect %s but found end of expression", ex)
+	}
+	return syntaxError(tok.pos, "expect %s but found `%s`", ex, tok.text)
+}
+
+func (p *parser) parseExpression(bp int) (left node, err error) {
+	if left, err = p.nud(p.next()); err != nil {
+		return
+	}
+
+	for bp < bindingPowers[p.peek().kind] {
+		if left, err = p.led(p.next(), left); err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+func (p *parser) nud(tok token) (n node, err error) {
+	switch tok.kind {
+	case _TokLiteral, _TokRawStr:
+		return literalNode{tok.value}, nil
+
+	case _TokIdent:
+		return fieldNode{tok.text}, nil
+
+	case _TokQuoted:
+		if p.peek().kind == _TokLParen {
+			return nil, syntaxError(tok.pos, "quoted identifier %s can't be a function name", tok.text)
+		}
+		name, _ := tok.value.String()
+		return fieldNode{name}, nil
+
+	case _TokStar:
+		right, err := p.parseProjectionRHS(bindingPowers[_TokStar])
+		if err != nil {
+			return nil, err
+		}
+		return valueProjectionNode{currentNode{}, right}, nil
+
+	case _TokFilter:
+		return p.parseFilter(currentNode{})
+
+	case _TokLBrace:
+		return p.parseMultiSelectHash()
+
+	case _TokFlatten:
+		right, err := p.parseProjectionRHS(bindingPowers[_TokFlatten])
+		if err != nil {
+			return nil, err
+		}
+		return projectionNode{flattenNode{currentNode{}}, right}, nil
+
+	case _TokLBracket:
+		switch {
+		case p.lookahead(0) == _TokNumber || p.lookahead(0) == _TokColon:
+			right, err := p.parseIndexExpression()
+			if err != nil {
+				return nil, err
+			}
+			return p.projectIfSlice(currentNode{}, right)
+
+		case p.lookahead(0) == _TokStar && p.lookahead(1) == _TokRBracket:
+			p.next()
+			p.next()
+			right, err := p.parseProjectionRHS(bindingPowers[_TokStar])
+			if err != nil {
+				return nil, err
+			}
+			return projectionNode{currentNode{}, right}, nil
+		}
+		return p.parseMultiSelectList()
+
+	case _TokCurrent:
+		return currentNode{}, nil
+
+	case _TokExpref:
+		child, err := p.parseExpression(0)
+		if err != nil {
+			return nil, err
+		}
+		return exprefNode{child}, nil
+
+	case _TokNot:
+		child, err := p.parseExpression(bindingPowers[_TokNot])
+		if err != nil {
+			return nil, err
+		}
+		return notNode{child}, nil
+
+	case _TokLParen:
+		child, err := p.parseExpression(0)
+		if err != nil {
+			return nil, err
+		}
+		return child, p.expect(_TokRParen, "`)`")
+	}
+
+	return nil, unexpected(tok, "an expression")
+}
+
+func (p *parser) led(tok token, left node) (n node, err error) {
+	switch tok.kind {
+	case _TokDot:
+		if p.peek().kind == _TokStar {
+			p.next()
+			right, err := p.parseProjectionRHS(bindingPowers[_TokDot])
+			if err != nil {
+				return nil, err
+			}
+			return valueProjectionNode{left, right}, nil
+		}
+		right, err := p.parseDotRHS(bindingPowers[_TokDot])
+		if err != nil {
+			return nil, err
+		}
+		return subexprNode{left, right}, nil
+
+	case _TokPipe, _TokOr, _TokAnd:
+		right, err := p.parseExpression(bindingPowers[tok.kind])
+		if err != nil {
+			return nil, err
+		}
+		switch tok.kind {
+		case _TokPipe:
+			return pipeNode{left, right}, nil
+		case _TokOr:
+			return orNode{left, right}, nil
+		}
+		return andNode{left, right}, nil
+
+	case _TokEQ, _TokNE, _TokLT, _TokLTE, _TokGT, _TokGTE:
+		right, err := p.parseExpression(bindingPowers[tok.kind])
+		if err != nil {
+			return nil, err
+		}
+		return comparatorNode{tok.kind, left, right}, nil
+
+	case _TokLParen:
+		field, ok := left.(fieldNode)
+		if !ok {
+			return nil, syntaxError(tok.pos, "expect a function name before `(`")
+		}
+		return p.parseFunction(field.name, tok.pos)
+
+	case _TokFilter:
+		return p.parseFilter(left)
+
+	case _TokFlatten:
+		right, err := p.parseProjectionRHS(bindingPowers[_TokFlatten])
+		if err != nil {
+			return nil, err
+		}
+		return projectionNode{flattenNode{left}, right}, nil
+
+	case _TokLBracket:
+		switch {
+		case p.lookahead(0) == _TokNumber || p.lookahead(0) == _TokColon:
+			right, err := p.parseIndexExpression()
+			if err != nil {
+				return nil, err
+			}
+			return p.projectIfSlice(left, right)
+
+		case p.lookahead(0) == _TokStar && p.lookahead(1) == _TokRBracket:
+			p.next()
+			p.next()
+			right, err := p.parseProjectionRHS(bindingPowers[_TokStar])
+			if err != nil {
+				return nil, err
+			}
+			return projectionNode{left, right}, nil
+		}
+		return nil, unexpected(p.peek(), "a number, `:` or `*`")
+	}
+
+	return nil, unexpected(tok, "an operator")
+}
+
+func (p *parser) parseFunction(name string, pos int) (n node, err error) {
+	var args []node
+	for p.peek().kind != _TokRParen {
+		var arg node
+		if arg, err = p.parseExpression(0); err != nil {
+			return
+		}
+		args = append(args, arg)
+
+		if p.peek().kind == _TokComma {
+			p.next()
+			if p.peek().kind == _TokRParen {
+				return nil, unexpected(p.peek(), "an argument")
+			}
+		} else if p.peek().kind != _TokRParen {
+			return nil, unexpected(p.peek(), "`,` or `)`")
+		}
+	}
+	p.next()
+
+	fn, ok := functions[name]
+	if !ok {
+		return nil, &Error{Kind: ERR_UNKNOWN_FUNCTION, Pos: pos, Msg: "unknown function " + name}
+	}
+
+	if err = fn.checkArity(len(args)); err != nil {
+		return
+	}
+
+	return functionNode{name, fn, args}, nil
+}
+
+// parseIndexExpression parses `[0]` or `[start:stop:step]` after the `[`
+func (p *parser) parseIndexExpression() (n node, err error) {
+	if p.lookahead(0) == _TokColon || p.lookahead(1) == _TokColon {
+		return p.parseSlice()
+	}
+
+	tok := p.next()
+	if err = p.expect(_TokRBracket, "`]`"); err != nil {
+		return
+	}
+
+	return indexNode{tok.num}, nil
+}
+
+func (p *parser) parseSlice() (n node, err error) {
+	var parts [3]*int
+	var i = 0
+
+	for {
+		tok := p.peek()
+		switch {
+		case tok.kind == _TokRBracket:
+			p.next()
+			if parts[2] != nil && *parts[2] == 0 {
+				return nil, &Error{Kind: ERR_INVALID_VALUE, Pos: tok.pos, Msg: "slice step can't be 0"}
+			}
+			return sliceNode{parts[0], parts[1], parts[2]}, nil
+
+		case tok.kind == _TokColon:
+			p.next()
+			i++
+			if i > 2 {
+				return nil, unexpected(tok, "`]`")
+			}
+
+		case tok.kind == _TokNumber:
+			p.next()
+			num := tok.num
+			parts[i] = &num
+
+		default:
+			return nil, unexpected(tok, "a number, `:` or `]`")
+		}
+	}
+}
+
+func (p *parser) projectIfSlice(left node, right node) (n node, err error) {
+	index := indexExprNode{left, right}
+	if _, ok := right.(sliceNode); !ok {
+		return index, nil
+	}
+
+	rhs, err := p.parseProjectionRHS(bindingPowers[_TokStar])
+	if err != nil {
+		return
+	}
+
+	return projectionNode{index, rhs}, nil
+}
+
+func (p *parser) parseFilter(left node) (n node, err error) {
+	cond, err := p.parseExpression(0)
+	if err != nil {
+		return
+	}
+
+	if err = p.expect(_TokRBracket, "`]`"); err != nil {
+		return
+	}
+
+	var right node = currentNode{}
+	if p.peek().kind != _TokFlatten {
+		if right, err = p.parseProjectionRHS(bindingPowers[_TokFilter]); err != nil {
+			return
+		}
+	}
+
+	return filterNode{left, right, cond}, nil
+}
+
+func (p *parser) parseDotRHS(bp int) (n node, err error) {
+	switch p.peek().kind {
+	case _TokIdent, _TokQuoted, _TokStar:
+		return p.parseExpression(bp)
+	case _TokLBracket:
+		p.next()
+		return p.parseMultiSelectList()
+	case _TokLBrace:
+		p.next()
+		return p.parseMultiSelectHash()
+	}
+
+	return nil, unexpected(p.peek(), "an identifier, `[` or `{` after `.`")
+}
+
+func (p *parser) parseProjectionRHS(bp int) (n node, err error) {
+	switch kind := p.peek().kind; {
+	case bindingPowers[kind] < projectionStop:
+		return currentNode{}, nil
+	case kind == _TokLBracket, kind == _TokFilter:
+		return p.parseExpression(bp)
+	case kind == _TokDot:
+		p.next()
+		return p.parseDotRHS(bp)
+	}
+
+	return nil, unexpected(p.peek(), "`.`, `[` or `[?`")
+}
+
+func (p *parser) parseMultiSelectList() (n node, err error) {
+	var items []node
+	for {
+		var item node
+		if item, err = p.parseExpression(0); err != nil {
+			return
+		}
+		items = append(items, item)
+
+		tok := p.next()
+		if tok.kind == _TokRBracket {
+			return listNode{items}, nil
+		}
+		if tok.kind != _TokComma {
+			return nil, unexpected(tok, "`,` or `]`")
+		}
+	}
+}
+
+func (p *parser) parseMultiSelectHash() (n node, err error) {
+	var hash hashNode
+	for {
+		key := p.next()
+		if key.kind != _TokIdent && key.kind != _TokQuoted {
+			return nil, unexpected(key, "a key name")
+		}
+
+		name := key.text
+		if key.kind == _TokQuoted {
+			name, _ = key.value.String()
+		}
+
+		if err = p.expect(_TokColon, "`:`"); err != nil {
+			return
+		}
+
+		var value node
+		if value, err = p.parseExpression(0); err != nil {
+			return
+		}
+
+		hash.keys = append(hash.keys, name)
+		hash.values = append(hash.values, value)
+
+		tok := p.next()
+		if tok.kind == _TokRBrace {
+			return hash, nil
+		}
+		if tok.kind != _TokComma {
+			return nil, unexpected(tok, "`,` or `}`")
+		}
+	}
+}
@@ -8,6 +8,7 @@ package jzon
 import (
 	"errors"
 	"fmt"
+	"sort"
 )
 
 // ValueType is the alias of int
@@ -259,28 +260,115 @@ func (jz *Jzon) Delete(k string) (err error) {
 	return nil
 }
 
-// Remove removes an index in an array, it's safe to delete an index
-// doesn't exist, if it's not an array, an error will be thrown out
+// Remove removes an index in an array, the elements after it are moved forward. if
+// it's not an array or the index is out of bound, an error will be thrown out
 func (jz *Jzon) Remove(i int) (err error) {
 	if jz.Type != JzTypeArr {
 		return expectTypeOf(JzTypeArr, jz.Type)
 	}
 
-	if i > len(jz.data.([]*Jzon)) || i < 0 {
-		return errors.New("index is out of bounds")
+	if i < 0 || i >= len(jz.data.([]*Jzon)) {
+		return errors.New("index is out of bound")
+	}
+
+	_, err = jz.Splice(i, 1)
+	return
+}
+
+// InsertAt inserts a node before the element at the index in an array, the index
+// can be the length of the array, which means appending. if it's not an array or
+// the index is out of bound, an error will be thrown out
+func (jz *Jzon) InsertAt(i int, v *Jzon) (err error) {
+	if jz.Type != JzTypeArr {
+		return expectTypeOf(JzTypeArr, jz.Type)
+	}
+
+	if i < 0 || i > len(jz.data.([]*Jzon)) {
+		return errors.New("index is out of bound")
+	}
+
+	_, err = jz.Splice(i, 0, v)
+	return
+}
+
+// SetAt replaces the element at the index in an array, if it's not an
+// array or the index is out of bound, an error will be thrown out
+func (jz *Jzon) SetAt(i int, v *Jzon) (err error) {
+	if jz.Type != JzTypeArr {
+		return expectTypeOf(JzTypeArr, jz.Type)
+	}
+
+	arr := jz.data.([]*Jzon)
+	if i < 0 || i >= len(arr) {
+		return errors.New("index is out of bound")
+	}
+
+	arr[i] = v
+	return nil
+}
+
+// Splice removes deleteCount elements from the index start in an array, inserts
+// items at start, and returns the removed elements, as splice() in JavaScript.
+// deleteCount is truncated to the number of the elements after start. if it's
+// not an array, or start is out of bound, or deleteCount is negative, an error
+// will be thrown out
+func (jz *Jzon) Splice(start int, deleteCount int, items ...*Jzon) (removed []*Jzon, err error) {
+	if jz.Type != JzTypeArr {
+		return nil, expectTypeOf(JzTypeArr, jz.Type)
 	}
 
-	newArr := jz.data.([]*Jzon)[0:i]
+	arr := jz.data.([]*Jzon)
+	if start < 0 || start > len(arr) || deleteCount < 0 {
+		return nil, errors.New("index is out of bound")
+	}
+
+	end := min(start+deleteCount, len(arr))
+	removed = append([]*Jzon(nil), arr[start:end]...)
+
+	spliced := make([]*Jzon, 0, len(arr)-len(removed)+len(items))
+	spliced = append(spliced, arr[:start]...)
+	spliced = append(spliced, items...)
+	spliced = append(spliced, arr[end:]...)
+	jz.data = spliced
 
-	for _, v := range jz.data.([]*Jzon)[i:] {
-		newArr = append(newArr, v)
+	return removed, nil
+}
+
+// Reverse reverses the elements of an array in place, if it's
+// not an array, an error will be thrown out
+func (jz *Jzon) Reverse() (err error) {
+	if jz.Type != JzTypeArr {
+		return expectTypeOf(JzTypeArr, jz.Type)
 	}
 
-	jz.data = newArr
+	arr := jz.data.([]*Jzon)
+	for i, j := 0, len(arr)-1; i < j; i, j = i+1, j-1 {
+		arr[i], arr[j] = arr[j], arr[i]
+	}
 
 	return nil
 }
 
+// SortBy sorts the elements of an array in place with the less function, the sort
+// is stable, so equal elements keep their order. if it's not an array, an error
+// will be thrown out
+func (jz *Jzon) SortBy(less func(a, b *Jzon) bool) (err error) {
+	if jz.Type != JzTypeArr {
+		return expectTypeOf(JzTypeArr, jz.Type)
+	}
+
+	arr := jz.data.([]*Jzon)
+	sort.SliceStable(arr, func(i, j int) bool { return less(arr[i], arr[j]) })
+	return nil
+}
+
+// Sort sorts the elements of an array in place in the total ordering of `Compare()`,
+// so arrays of mixed types can be sorted. if it's not an array, an error will be
+// thrown out
+func (jz *Jzon) Sort() (err error) {
+	return jz.SortBy(func(a, b *Jzon) bool { return Compare(a, b) < 0 })
+}
+
 // AMap is just map for array, if it's not an array, an error will be thrown out
 func (jz *Jzon) AMap(itFunc func(g *Jzon) Any) (res []Any, err error) {
 	if jz.Type != JzTypeArr {
@@ -343,21 +431,21 @@ func (jz *Jzon) OMap(itFunc func(key string, g *Jzon) Any) (res []Any, err error
 	return res, nil
 }
 
-// OFilter is just filter for object, if it's not an object, an error will be thrown out
+// OFilter is just filter for object, if it's not an object, an error will be thrown out.
+// the result is a new object sharing children with the original, as `AFilter()` does
 func (jz *Jzon) OFilter(predictFunc func(key string, value *Jzon) bool) (res *Jzon, err error) {
 	if jz.Type != JzTypeObj {
 		return res, expectTypeOf(JzTypeObj, jz.Type)
 	}
 
-	var tmp = *jz
+	res = New(JzTypeObj)
 
-	for k, v := range tmp.data.(map[string]*Jzon) {
-		if !predictFunc(k, v) {
-			tmp.Delete(k)
+	for k, v := range jz.data.(map[string]*Jzon) {
+		if predictFunc(k, v) {
+			res.Insert(k, v)
 		}
 	}
 
-	res = &tmp
 	return res, nil
 }
 
@@ -1,14 +1,20 @@
 package jzon
 
 import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"math"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
 	"testing"
+	"testing/fstest"
 )
 
 const deepJSON = `
@@ -52,6 +58,60 @@ const deepJSON = `
 	"key-escaped-.[];-key": "escape success"
 }`
 
+// jzon.go
+
+func TestArrayEditing(t *testing.T) {
+	jz, _ := Parse([]byte(`[1, 2, 3]`))
+
+	if err := jz.Remove(1); err != nil || jz.Compact() != "[1,3]" {
+		t.Errorf("expect [1,3], but got %s (%v)", jz.Compact(), err)
+	}
+
+	if err := jz.Remove(2); err == nil {
+		t.Errorf("expect error for removing index == length")
+	}
+
+	jz.InsertAt(0, NewFromAny(0))
+	jz.InsertAt(3, NewFromAny(4))
+	if jz.Compact() != "[0,1,3,4]" {
+		t.Errorf("expect [0,1,3,4], but got %s", jz.Compact())
+	}
+
+	if err := jz.InsertAt(5, NewFromAny(5)); err == nil {
+		t.Errorf("expect error for inserting out of bound")
+	}
+
+	if err := jz.SetAt(4, NewFromAny(5)); err == nil {
+		t.Errorf("expect error for setting out of bound")
+	}
+
+	jz.SetAt(0, NewFromAny(-1))
+	removed, err := jz.Splice(1, 2, NewFromAny("a"), NewFromAny("b"), NewFromAny("c"))
+	if err != nil || jz.Compact() != `[-1,"a","b","c",4]` || len(removed) != 2 {
+		t.Errorf("expect [-1,\"a\",\"b\",\"c\",4], but got %s (%v)", jz.Compact(), err)
+	}
+
+	if removed, _ = jz.Splice(3, 10); len(removed) != 2 || jz.Compact() != `[-1,"a","b"]` {
+		t.Errorf("expect deleteCount to be truncated, but got %s", jz.Compact())
+	}
+
+	jz.Reverse()
+	if jz.Compact() != `["b","a",-1]` {
+		t.Errorf("expect reversed array, but got %s", jz.Compact())
+	}
+
+	mixed, _ := Parse([]byte(`[{"a": 1}, [2], "s", 1.5, 1, true, false, null]`))
+	mixed.Sort()
+	if mixed.Compact() != `[null,false,true,1,1.500000,"s",[2],{"a":1}]` {
+		t.Errorf("expect mixed array sorted by type, but got %s", mixed.Compact())
+	}
+
+	mixed.SortBy(func(a, b *Jzon) bool { return Compare(a, b) > 0 })
+	if mixed.Compact() != `[{"a":1},[2],"s",1.500000,1,true,false,null]` {
+		t.Errorf("expect mixed array sorted descending, but got %s", mixed.Compact())
+	}
+}
+
 // parser.go
 
 func TestParse(t *testing.T) {
@@ -71,6 +131,13 @@ func TestParse(t *testing.T) {
 		t.Error(err)
 	}
 
+	// trailing whitespaces must not run past the end of input
+	for _, s := range []string{"1 ", "[] \n", "{\"a\": true}\r\n", "null\t"} {
+		if _, err = Parse([]byte(s)); err != nil {
+			t.Errorf("expect %q to be parsed, but err is %v", s, err)
+		}
+	}
+
 	parseFile := func(path string) error {
 		content, err := ioutil.ReadFile(path)
 		if err != nil {
@@ -304,6 +371,19 @@ func TestParseUnicode(t *testing.T) {
 		t.Errorf("张    in hex is %x %x %x", zhang[0], zhang[1], zhang[2])
 		t.Errorf("expect kanji = 张, but kanji is %s", string(kanji))
 	}
+
+	// characters beyond the BMP are encoded as surrogate pairs
+	str, rem, err = parseUnicode([]byte("\\ud83d\\ude00x"))
+	if err != nil {
+		t.Error(err)
+	}
+	if string(str) != "😀" || string(rem) != "x" {
+		t.Errorf("expect 😀 and rem = x, but got %s and rem = %s", string(str), string(rem))
+	}
+
+	if _, _, err = parseUnicode([]byte("\\ud83d\\u0041")); err == nil {
+		t.Errorf("expect error on a high surrogate without the low one")
+	}
 }
 
 func TestParseHex4(t *testing.T) {
@@ -385,6 +465,23 @@ func TestParseNumeric(t *testing.T) {
 		t.Errorf("expect n = -12 but n = %d", n)
 	}
 
+	for s, expect := range map[string]float64{"-12.5": -12.5, "-0.25": -0.25, "-1.5e3": -1500,
+		"1.5e-27": 1.5e-27, "-0.1": -0.1, "0.1": 0.1, "1e-400": 0, "123456789.123456789": 123456789.123456789} {
+		n, f, isInt, rem, err = parseNumeric([]byte(s))
+		if err != nil {
+			t.Error(err)
+		}
+		if isInt || f != expect {
+			t.Errorf("expect f = %v but f = %v, isInt = %v", expect, f, isInt)
+		}
+	}
+
+	for _, s := range []string{"1e400", "-1e400"} {
+		if _, _, _, _, err = parseNumeric([]byte(s)); err == nil {
+			t.Errorf("expect error for %s overflowing a float", s)
+		}
+	}
+
 	n, f, isInt, rem, err = parseNumeric(more)
 	if err != nil {
 		t.Error(err)
@@ -487,99 +584,1847 @@ func TestSearch(t *testing.T) {
 	}
 }
 
-// utilities.go
-func TestCompact(t *testing.T) {
+func TestSet(t *testing.T) {
 	jz, err := Parse([]byte(deepJSON))
 	if err != nil {
 		t.Error(err)
 	}
 
-	fmt.Print(jz.Compact())
+	if err = jz.Set("$.key-array[2].number", NewFromAny(42)); err != nil {
+		t.Error(err)
+	}
+
+	if n, _ := jz.Query("$.key-array[2].number"); n == nil || n.data.(int64) != 42 {
+		t.Errorf("expect number = 42, but number is %v", n)
+	}
+
+	if err = jz.Set("$.key-object.new-key", NewFromAny("new")); err != nil {
+		t.Error(err)
+	}
+
+	if !jz.Search("$.key-object.new-key") {
+		t.Errorf("expect $.key-object.new-key exists")
+	}
+
+	err = jz.Set("$.key-object.missing.key", NewFromAny(1))
+	if err == nil || !strings.Contains(err.Error(), "$.key-object.missing") {
+		t.Errorf("expect error at $.key-object.missing, but err is %v", err)
+	}
+
+	if err = jz.Set("$.key-array[3]", NewFromAny(1)); err == nil {
+		t.Errorf("expect index out of bound error")
+	}
 }
 
-func TestFormat(t *testing.T) {
+func TestSetCreate(t *testing.T) {
+	jz := New(JzTypeObj)
+
+	if err := jz.SetCreate("$.a.b[2].c", NewFromAny("deep")); err != nil {
+		t.Error(err)
+	}
+
+	if compact := jz.Compact(); compact != `{"a":{"b":[null,null,{"c":"deep"}]}}` {
+		t.Errorf("unexpected result: %s", compact)
+	}
+
+	if err := jz.SetCreate("$.a.b.c", NewFromAny(1)); err == nil {
+		t.Errorf("expect error for setting a key in an array")
+	}
+}
+
+func TestUnset(t *testing.T) {
 	jz, err := Parse([]byte(deepJSON))
 	if err != nil {
 		t.Error(err)
 	}
 
-	fmt.Println()
-	fmt.Println()
-	fmt.Print(jz.Format(0, 2))
-	fmt.Println()
-	fmt.Println()
+	if err = jz.Unset("$.key-array[0]"); err != nil {
+		t.Error(err)
+	}
+
+	if l, _ := jz.data.(map[string]*Jzon)["key-array"].Length(); l != 2 {
+		t.Errorf("expect len(key-array) = 2, but len is %d", l)
+	}
+
+	if err = jz.Unset("$.key-object.key-o-o"); err != nil {
+		t.Error(err)
+	}
+
+	if jz.Search("$.key-object.key-o-o") {
+		t.Errorf("expect $.key-object.key-o-o doesn't exist")
+	}
+
+	if err = jz.Unset("$.key-object.key-o-o"); err == nil {
+		t.Errorf("expect error for unsetting a missing key")
+	}
+
+	if err = jz.Unset("$"); err == nil {
+		t.Errorf("expect error for unsetting the root")
+	}
 }
 
-func TestColoring(t *testing.T) {
+// path.go
+
+func TestCompilePath(t *testing.T) {
 	jz, err := Parse([]byte(deepJSON))
 	if err != nil {
 		t.Error(err)
 	}
 
-	jz.Coloring(os.Stdout)
-	fmt.Println()
+	p, err := CompilePath(`$.key-array[1].string`)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if p.String() != `$.key-array[1].string` {
+		t.Errorf("expect canonical path = $.key-array[1].string, but path is %s", p)
+	}
+
+	res, err := p.Get(jz)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if str, _ := res.String(); str != "another string 2" {
+		t.Errorf("expect str = another string 2, but str is %v", str)
+	}
+
+	p, err = CompilePath(`$.key-escaped-\.\[\]\;-key`)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if built := Root().Key("key-escaped-.[];-key"); built.String() != p.String() {
+		t.Errorf("expect built path = %s, but built path is %s", p, built)
+	}
+
+	if _, err = CompilePath("$.key[1.5]"); err == nil {
+		t.Errorf("expect error for float index")
+	}
 }
 
-// reflect.go
-type User struct {
-	Name     string         `jzon:"name"`
-	Pwd      string         `jzon:"pwd"`
-	Model    int            `jzon:"model"`
-	EmptyTag int            `jzon:","`
-	Nest     Nested         `jzon:"nested"`
-	Array    []int          `jzon:"array"`
-	Map      map[string]int `jzon:"map"`
-	NilSlice []byte         `jzon:","`
-	Ptr      *int           `jzon:"ptr"`
-	Bool     bool           `jzon:"bool"`
-	NoTag    int
+func TestPathGetAll(t *testing.T) {
+	jz, err := Parse([]byte(deepJSON))
+	if err != nil {
+		t.Error(err)
+	}
+
+	p, err := CompilePath("$.key-array[*].string")
+	if err != nil {
+		t.Error(err)
+	}
+
+	if _, err = p.Get(jz); err == nil {
+		t.Errorf("expect error for getting a wildcard path")
+	}
+
+	all := p.GetAll(jz)
+	if len(all) != 3 {
+		t.Errorf("expect 3 matches, but found %d", len(all))
+	}
+
+	for i, node := range all {
+		if str, _ := node.String(); str != "another string "+strconv.Itoa(i+1) {
+			t.Errorf("unexpected match at %d: %s", i, str)
+		}
+	}
+
+	built := Root().Key("key-array").Wildcard().Key("empty-object")
+	if n := len(built.GetAll(jz)); n != 3 {
+		t.Errorf("expect 3 matches, but found %d", n)
+	}
 }
 
-type ImpInterface int
+func TestQueryWithPaths(t *testing.T) {
+	jz, err := Parse([]byte(`{"a": [{"b": 1}, {"c": 2}, {"b": 3}], "it's": {"x": true, "y": false}}`))
+	if err != nil {
+		t.Error(err)
+	}
 
-func (i ImpInterface) SerializeJzon() string {
-	return "INT" + strconv.Itoa(int(i))
+	ms, err := jz.QueryWithPaths("$.a[*].b")
+	if err != nil {
+		t.Error(err)
+	}
+
+	if len(ms) != 2 {
+		t.Errorf("expect 2 matches, but found %d", len(ms))
+	}
+
+	if ms[1].Path != "$['a'][2]['b']" || ms[1].Key != "b" || ms[1].Index != -1 {
+		t.Errorf("unexpected match: %+v", ms[1])
+	}
+
+	ms, err = jz.QueryWithPaths("$.it's[*]")
+	if err != nil {
+		t.Error(err)
+	}
+
+	if len(ms) != 2 || ms[0].Path != `$['it\'s']['x']` || ms[1].Key != "y" {
+		t.Errorf("unexpected matches: %+v", ms)
+	}
+
+	ms, err = jz.QueryWithPaths("$.a[*]")
+	if err != nil {
+		t.Error(err)
+	}
+
+	if len(ms) != 3 || ms[2].Index != 2 || ms[2].Parent != jz.data.(map[string]*Jzon)["a"] {
+		t.Errorf("unexpected matches: %+v", ms)
+	}
+
+	if _, err = jz.QueryWithPaths("$.a["); err == nil {
+		t.Errorf("expect error for malformed path")
+	}
 }
 
-type Nested struct {
-	Inner string `jzon:"inner"`
+func TestPathSet(t *testing.T) {
+	jz := New(JzTypeObj)
+	p := Root().Key("a").Index(1)
+
+	if err := p.Set(jz, NewFromAny(1)); err == nil {
+		t.Errorf("expect error for setting a missing parent")
+	}
+
+	if err := p.SetCreate(jz, NewFromAny(1)); err != nil {
+		t.Error(err)
+	}
+
+	if compact := jz.Compact(); compact != `{"a":[null,1]}` {
+		t.Errorf("unexpected result: %s", compact)
+	}
+
+	arr, _ := Parse([]byte(`[1, 2]`))
+	if err := Root().Index(-1).Set(arr, NewFromAny(3)); err == nil {
+		t.Errorf("expect error for setting a negative index")
+	}
+	if err := Root().Index(-1).SetCreate(arr, NewFromAny(3)); err == nil {
+		t.Errorf("expect error for creating a negative index")
+	}
+	if _, err := Root().Index(-1).Get(arr); err == nil {
+		t.Errorf("expect error for getting a negative index")
+	}
+	for _, path := range []string{"$.a[50000000]", "$.b[1025]", "$.c[2000].d"} {
+		if _, ok := jz.SetCreate(path, NewFromAny(1)).(*PathError); !ok {
+			t.Errorf("expect PathError for padding too many nulls at %s", path)
+		}
+	}
+	if err := jz.SetCreate("$.b[1024]", NewFromAny(1)); err != nil || len(jz.MustArray("$.b")) != 1025 {
+		t.Errorf("expect padding up to SET_CREATE_MAX_GAP nulls, but got %v", err)
+	}
 }
 
-func TestSerialize(t *testing.T) {
-	user := User{
-		Name:  "ZuoXinyu",
-		Pwd:   "password",
-		Model: 1,
-		Nest:  Nested{Inner: "innerString"},
-		Array: []int{1, 2, 3, 4, 5},
-		Map: map[string]int{
-			"one":   1,
-			"two":   2,
-			"three": 3,
-			"four":  4,
-		},
+func TestQuotedPathKey(t *testing.T) {
+	jz, err := Parse([]byte(`{"weird.key": 1, "a]b": 2, "": 3, "it's": 4, "键.名": {"值": 5}}`))
+	if err != nil {
+		t.Fatal(err)
 	}
-	jz, err := Serialize(user)
+
+	var cases = []struct {
+		path string
+		n    int64
+	}{
+		{`$['weird.key']`, 1},
+		{`$["a]b"]`, 2},
+		{`$['']`, 3},
+		{`$['it\'s']`, 4},
+		{`$["it's"]`, 4},
+		{`$['\u952e.\u540d'].值`, 5},
+		{`$.键\.名['值']`, 5},
+	}
+
+	for _, c := range cases {
+		res, err := jz.Query(c.path)
+		if err != nil {
+			t.Errorf("fail on %s: %v", c.path, err)
+			continue
+		}
+
+		if n, _ := res.Integer(); n != c.n {
+			t.Errorf("expect %d for %s, but got %d", c.n, c.path, n)
+		}
+	}
+
+	if p := Root().Key(""); p.String() != "$['']" {
+		t.Errorf("expect $[''], but path is %s", p)
+	}
+
+	ms, err := jz.QueryWithPaths("$[*]")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, m := range ms {
+		p, err := CompilePath(m.Path)
+		if err != nil {
+			t.Errorf("fail on normalized path %s: %v", m.Path, err)
+			continue
+		}
+
+		if res, _ := p.Get(jz); res != m.Node {
+			t.Errorf("expect normalized path %s to address the same node", m.Path)
+		}
+	}
+
+	_, err = CompilePath(`$.键[x]`)
+	if perr, ok := err.(*PathError); !ok || perr.Offset != 4 || perr.Segment != "[x" {
+		t.Errorf("expect error at offset 4, but got %v", err)
+	}
+
+	if _, err = CompilePath(`$['unclosed`); err == nil {
+		t.Errorf("expect error for unclosed quoted key")
+	}
+}
+
+func TestPathError(t *testing.T) {
+	var syntaxCases = []struct {
+		path    string
+		offset  int
+		segment string
+	}{
+		{"a.b", 0, "a"},
+		{"$.", 2, "."},
+		{"$.a[x]", 4, "[x"},
+		{"$.a[1", 5, "[1"},
+		{"$.a[1]b", 6, "[1]b"},
+		{`$.a\`, 3, `.a\`},
+		{`$.a\q`, 3, `.a\`},
+		{"$.日本.", 5, "."},
+		{"$.日本[x]", 5, "[x"},
+	}
+
+	for _, c := range syntaxCases {
+		_, err := CompilePath(c.path)
+		perr, ok := err.(*PathError)
+		if !ok {
+			t.Errorf("expect PathError for %s, but err is %v", c.path, err)
+			continue
+		}
+
+		if perr.Offset != c.offset || perr.Segment != c.segment {
+			t.Errorf("expect offset = %d, segment = %s for %s, but got %d, %s",
+				c.offset, c.segment, c.path, perr.Offset, perr.Segment)
+		}
+	}
+
+	jz, err := Parse([]byte(deepJSON))
 	if err != nil {
 		t.Error(err)
 	}
 
-	jz.Print()
-	fmt.Printf("\n")
+	_, err = jz.Query("$.key-object.missing.key")
+	perr, ok := err.(*PathError)
+	if !ok {
+		t.Fatalf("expect PathError, but err is %v", err)
+	}
 
-	m := map[ImpInterface]int{
-		4: 1,
-		3: 2,
-		2: 1,
+	if perr.Offset != -1 || perr.Depth != 1 || perr.Segment != ".missing" {
+		t.Errorf("expect failure on .missing at depth 1, but got %d, %s at %d", perr.Depth, perr.Segment, perr.Offset)
 	}
+}
 
-	jz1, err := Serialize(m)
+// compare.go
+
+func TestCompare(t *testing.T) {
+	var cases = []struct {
+		a, b   *Jzon
+		expect int
+	}{
+		{NewFromAny(int64(1<<53 + 1)), NewFromAny(float64(1 << 53)), 1},
+		{NewFromAny(float64(1 << 53)), NewFromAny(int64(1 << 53)), 0},
+		{NewFromAny(int64(1<<53 + 1)), NewFromAny(int64(1 << 53)), 1},
+		{NewFromAny(int64(math.MaxInt64)), NewFromAny(float64(math.MaxInt64)), -1},
+		{NewFromAny(int64(math.MinInt64)), NewFromAny(float64(math.MinInt64)), 0},
+		{NewFromAny(int64(2)), NewFromAny(2.5), -1},
+		{NewFromAny(-2.5), NewFromAny(int64(-2)), -1},
+		{NewFromAny(int64(-3)), NewFromAny(-2.5), -1},
+		{NewFromAny(math.Inf(-1)), NewFromAny(int64(math.MinInt64)), -1},
+	}
+
+	for _, c := range cases {
+		if got := Compare(c.a, c.b); got != c.expect {
+			t.Errorf("expect Compare(%v, %v) = %d, but got %d", c.a.data, c.b.data, c.expect, got)
+		}
+		if got := Compare(c.b, c.a); got != -c.expect {
+			t.Errorf("expect Compare(%v, %v) = %d, but got %d", c.b.data, c.a.data, -c.expect, got)
+		}
+	}
+}
+
+// transform.go
+
+func TestTransform(t *testing.T) {
+	jz, err := Parse([]byte(`{
+		"users": [
+			{"name": "alice", "age": 31, "tags": ["admin"]},
+			{"name": "bob", "age": 25, "tags": []},
+			{"name": "carol", "age": 42, "tags": ["ops", "dev"]}
+		],
+		"meta": {"total": 3, "page": 1}
+	}`))
 	if err != nil {
 		t.Error(err)
 	}
 
-	jz1.Print()
-	fmt.Printf("\n")
+	cases := []struct {
+		program string
+		expect  string
+	}{
+		{`.meta.total`, `3`},
+		{`.users[1].name`, `"bob"`},
+		{`.users[-1].name`, `"carol"`},
+		{`[.users[].name]`, `["alice","bob","carol"]`},
+		{`.users | map(select(.age > 30) | .name)`, `["alice","carol"]`},
+		{`[.users[] | {name, n: (.tags | length)}] | .[2] | .n`, `2`},
+		{`.meta | keys`, `["page","total"]`},
+		{`.meta | to_entries | .[0] | [.key, .value]`, `["page",1]`},
+		{`.meta | with_entries(select(.key == "page"))`, `{"page":1}`},
+		{`[.users[] | if .age < 30 then "young" elif .age < 40 then "adult" else "senior" end]`, `["adult","young","senior"]`},
+		{`.users[0] | "\(.name) is \(.age)"`, `"alice is 31"`},
+		{`[.users[].age] | add / length`, `32.666667`},
+		{`.meta.total * 2 + 1, .meta.total % 2`, `7 1`},
+		{`[.users[].tags[]] | sort`, `["admin","dev","ops"]`},
+		{`.missing // "default"`, `"default"`},
+		{`.users[1:] | length`, `2`},
+		{`{(.users[0].name): .meta.page}`, `{"alice":1}`},
+		{`[.users[] | .name | select(. != "bob")]`, `["alice","carol"]`},
+		{`.meta | has("page"), has("none")`, `true false`},
+		{`[..] | length`, `20`},
+		{`.meta | tostring`, `"{\"page\":1,\"total\":3}"`},
+		{`[.users[].age / 2 | tostring]`, `["15.5","12.5","21"]`},
+		{`.meta | map(. * 10)`, `[10,30]`},
+	}
+
+	for _, c := range cases {
+		outs, err := Transform(c.program, jz)
+		if err != nil {
+			t.Errorf("program %s: %v", c.program, err)
+			continue
+		}
+
+		// outputs are compared with sorted keys, since the compact form follows the order of maps
+		var ss []string
+		for _, out := range outs {
+			var v interface{}
+			if err = json.Unmarshal([]byte(out.Compact()), &v); err != nil {
+				t.Errorf("program %s: %v", c.program, err)
+			}
+			b, _ := json.Marshal(v)
+			ss = append(ss, string(b))
+		}
+
+		if got := strings.Join(ss, " "); got != c.expect {
+			t.Errorf("program %s: expect %s, but got %s", c.program, c.expect, got)
+		}
+	}
+
+	for program, expect := range map[string]string{`"\u00e9"`: "é", `"\ud83d\ude00!"`: "😀!", `"a\u005c"`: `a\`} {
+		outs, err := Transform(program, jz)
+		if err != nil || len(outs) != 1 || outs[0].data != expect {
+			t.Errorf("program %s: expect %s, but got %v (%v)", program, expect, outs, err)
+		}
+	}
+
+	for _, bad := range []string{`"\u`, `"\u12`, `"\u12"`, `"\ud83d"`, `"\ud83d\u0041"`} {
+		if _, err := Transform(bad, jz); err == nil {
+			t.Errorf("expect error for program %s", bad)
+		}
+	}
+
+	// programs are compiled without any shared state
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if outs, err := Transform(`"\u00e9 \(.meta.page)"`, jz); err != nil || outs[0].data != "é 1" {
+				t.Errorf("expect é 1 from concurrent transforms, but got %v (%v)", outs, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	for _, bad := range []string{`.users[`, `map(.)(`, `undefined_fn`, `.meta | .[0]`, `"unterminated`, `.meta | map(keys)`, `.users | map(keys | .[0] | keys)`, `.meta.page | map(.)`} {
+		if _, err := Transform(bad, jz); err == nil {
+			t.Errorf("expect error for program %s", bad)
+		}
+	}
+}
+
+// accessor.go
+
+func TestGetters(t *testing.T) {
+	jz, _ := Parse([]byte(`{"s": "str", "n": 42, "f": 1.5, "b": true, "a": [1, 2], "ns": "42", "nf": "2.0", "big": 9007199254740993}`))
+
+	if s := jz.GetString("$.s", "def"); s != "str" {
+		t.Errorf("expect str, but got %s", s)
+	}
+	if s := jz.GetString("$.n", "def"); s != "def" {
+		t.Errorf("expect default for a wrong type, but got %s", s)
+	}
+	if n := jz.GetInt("$.missing", -1); n != -1 {
+		t.Errorf("expect default for a missing node, but got %d", n)
+	}
+	if n := jz.GetInt("$.n", -1); n != 42 {
+		t.Errorf("expect 42, but got %d", n)
+	}
+	if f := jz.GetFloat("$.f", 0); f != 1.5 {
+		t.Errorf("expect 1.5, but got %f", f)
+	}
+	if f := jz.GetFloat("$.n", 0); f != 42 {
+		t.Errorf("expect an integer to be converted, but got %f", f)
+	}
+	if f := jz.GetFloat("$.big", -1); f != -1 {
+		t.Errorf("expect default for an integer which can't be a float exactly, but got %f", f)
+	}
+	if b := jz.GetBool("$.b", false); !b {
+		t.Errorf("expect true")
+	}
+	if a := jz.GetArray("$.a", nil); len(a) != 2 {
+		t.Errorf("expect an array of 2 elements, but got %v", a)
+	}
+
+	if n := jz.MustInt("$.n"); n != 42 {
+		t.Errorf("expect 42, but got %d", n)
+	}
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("expect MustString to panic on a wrong type")
+			}
+		}()
+		jz.MustString("$.n")
+	}()
+
+	// MustFloat converts integers by the same rule as GetFloat
+	for _, path := range []string{"$.f", "$.n", "$.big", "$.s"} {
+		var got float64
+		var panicked bool
+		func() {
+			defer func() { panicked = recover() != nil }()
+			got = jz.MustFloat(path)
+		}()
+		if def := jz.GetFloat(path, -1); panicked != (def == -1) || !panicked && got != def {
+			t.Errorf("expect MustFloat and GetFloat to agree at %s, but got %f and %f (panicked: %v)", path, got, def, panicked)
+		}
+	}
+
+	if f, err := jz.AsFloat("$.n", false); err != nil || f != 42 {
+		t.Errorf("expect 42.0, but got %f (%v)", f, err)
+	}
+	if _, err := jz.AsInt("$.f", false); err == nil {
+		t.Errorf("expect error for converting 1.5 to an integer")
+	}
+	if _, err := jz.AsInt("$.ns", false); err == nil {
+		t.Errorf("expect error for converting a string without lenient")
+	}
+	if n, err := jz.AsInt("$.ns", true); err != nil || n != 42 {
+		t.Errorf("expect 42, but got %d (%v)", n, err)
+	}
+	if n, err := jz.AsInt("$.nf", true); err != nil || n != 2 {
+		t.Errorf("expect 2, but got %d (%v)", n, err)
+	}
+	if s, err := jz.AsString("$.f", true); err != nil || s != "1.5" {
+		t.Errorf("expect \"1.5\", but got %s (%v)", s, err)
+	}
+	if b, err := jz.AsBool("$.n", true); err != nil || !b {
+		t.Errorf("expect true, but got %v (%v)", b, err)
+	}
+}
+
+// flatten.go
+
+func TestFlatten(t *testing.T) {
+	jz, _ := Parse([]byte(`{"a": {"b": [1, {"c": "d"}], "e": {}}, "f.g": true, "h": []}`))
+
+	flat := jz.Flatten(".")
+	expected := map[string]string{
+		"a.b.0":   "1",
+		"a.b.1.c": `"d"`,
+		"a.e":     "{}",
+		"f.g":     "true",
+		"h":       "[]",
+	}
+	if len(flat) != len(expected) {
+		t.Errorf("expect %d keys, but got %v", len(expected), flat)
+	}
+	for k, v := range expected {
+		if flat[k] == nil || flat[k].Compact() != v {
+			t.Errorf("expect %s = %s, but got %v", k, v, flat[k])
+		}
+	}
+
+	opts := FlattenOptions{Separator: ".", BracketIndex: true, Escape: true}
+	flat = jz.FlattenWith(opts)
+	for _, k := range []string{`a.b[0]`, `a.b[1].c`, `f\.g`} {
+		if flat[k] == nil {
+			t.Errorf("expect key %s in %v", k, flat)
+			continue
+		}
+		if v, err := jz.Query("$." + k); err != nil || v != flat[k] {
+			t.Errorf("expect $.%s to be a valid path (%v)", k, err)
+		}
+	}
+
+	back, err := UnflattenWith(flat, opts)
+	if err != nil || !back.Equal(jz) {
+		t.Errorf("expect round trip, but got %v (%v)", back, err)
+	}
+
+	flat = jz.FlattenWith(FlattenOptions{Separator: "__", Escape: true})
+	if back, err = UnflattenWith(flat, FlattenOptions{Separator: "__", Escape: true}); err != nil || !back.Equal(jz) {
+		t.Errorf("expect round trip with custom separator, but got %v (%v)", back, err)
+	}
+
+	sparse := map[string]*Jzon{"x.2": NewFromAny(1), "y": NewFromAny("s")}
+	if back, err = Unflatten(sparse, "."); err != nil || back.MustArray("$.x")[0].Type != JzTypeNul {
+		t.Errorf("expect missing elements padded with null, but got %v (%v)", back, err)
+	}
+
+	conflict := map[string]*Jzon{"a": NewFromAny(1), "a.b": NewFromAny(2)}
+	if _, err = Unflatten(conflict, "."); err == nil {
+		t.Errorf("expect error for conflicting keys")
+	}
+
+	for _, bad := range []map[string]*Jzon{
+		{"a[-1]": NewFromAny(1)},
+		{"a[1000000000]": NewFromAny(1)},
+		{"a[1026]": NewFromAny(1), "b": NewFromAny(2)},
+	} {
+		if _, err = UnflattenWith(bad, FlattenOptions{BracketIndex: true}); err == nil {
+			t.Errorf("expect error for bad indices in %v", bad)
+		}
+	}
+
+	if _, err = Unflatten(map[string]*Jzon{"a.1000000000": NewFromAny(1)}, "."); err == nil {
+		t.Errorf("expect error for an index far beyond the number of keys")
+	}
+
+	for _, s := range []string{`[1, [2, {"a": 3}], []]`, `{"": 1}`, `{"": {"": [1, {"": 2}]}, "x": 3}`,
+		`{"a": {"": 2}, "\"\"": 4, "q\"": 5, "": {}}`, `[{"": []}]`} {
+		doc, _ := Parse([]byte(s))
+		for _, opts := range []FlattenOptions{{BracketIndex: true, Escape: true}, {Escape: true}, {Separator: "/", Escape: true}} {
+			if back, err = UnflattenWith(doc.FlattenWith(opts), opts); err != nil || !back.Equal(doc) {
+				t.Errorf("expect round trip of %s with %+v, but got %v (%v)", s, opts, back, err)
+			}
+		}
+	}
+
+	leaf := New(JzTypeObj)
+	if _, err = Unflatten(map[string]*Jzon{"a": leaf, "a.b": NewFromAny(1)}, "."); err == nil || leaf.Compact() != "{}" {
+		t.Errorf("expect error for a key under an empty object, and the object unchanged, but got %s (%v)", leaf.Compact(), err)
+	}
+
+	long := New(JzTypeArr)
+	for i := 0; i < 3000; i++ {
+		long.Append(NewFromAny(i))
+	}
+	if back, err = Unflatten(long.Flatten("."), "."); err != nil || !back.Equal(long) {
+		t.Errorf("expect round trip of a long array, but got %v", err)
+	}
+
+	arr, _ := Parse([]byte(`[1, [2]]`))
+	if back, err = UnflattenWith(arr.FlattenWith(FlattenOptions{BracketIndex: true}), FlattenOptions{BracketIndex: true}); err != nil || !back.Equal(arr) {
+		t.Errorf("expect a root array without escaping, but got %v (%v)", back, err)
+	}
+}
+
+// schema.go
+
+func TestInferSchema(t *testing.T) {
+	a, _ := Parse([]byte(`{"id": 1, "kind": "user", "score": 1.5, "tags": ["x"], "name": "ann"}`))
+	b, _ := Parse([]byte(`{"id": 7, "kind": "user", "score": 3, "tags": [], "name": null}`))
+	c, _ := Parse([]byte(`{"id": 3, "kind": "bot", "tags": ["y", 1]}`))
+	d, _ := Parse([]byte(`{"id": 4, "kind": "bot", "tags": ["z"]}`))
+
+	schema := InferSchema(a, b, c, d)
+
+	cases := map[string]string{
+		"$.type":                            `"object"`,
+		"$.properties.id.type":              `"integer"`,
+		"$.properties.id.minimum":           `1`,
+		"$.properties.id.maximum":           `7`,
+		"$.properties.score.type":           `"number"`,
+		"$.properties.score.minimum":        `1.500000`,
+		"$.properties.score.maximum":        `3`,
+		"$.properties.kind.enum":            `["bot","user"]`,
+		"$.properties.name.type":            `["null","string"]`,
+		"$.properties.tags.maxItems":        `2`,
+		"$.properties.tags.items.type":      `["integer","string"]`,
+		"$.properties.tags.items.minLength": `1`,
+		"$.required":                        `["id","kind","tags"]`,
+	}
+
+	for path, expected := range cases {
+		v, err := schema.Query(path)
+		if err != nil {
+			t.Errorf("expect %s in %s, but got %v", path, schema.Compact(), err)
+			continue
+		}
+		if v.Compact() != expected {
+			t.Errorf("expect %s = %s, but got %s", path, expected, v.Compact())
+		}
+	}
+
+	if _, err := schema.Query("$.properties.id.enum"); err == nil {
+		t.Errorf("expect no enum for values without repetition")
+	}
+
+	big, _ := Parse([]byte(`9007199254740993`))
+	if n := InferSchema(big).GetInt("$.maximum", 0); n != 9007199254740993 {
+		t.Errorf("expect maximum = 9007199254740993, but got %d", n)
+	}
+
+	content, err := ioutil.ReadFile("data/twitter.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tw, _ := Parse(content)
+	schema = InferSchema(tw.MustArray("$.statuses")...)
+	if s := schema.GetString("$.properties.metadata.properties.result_type.type", ""); s != "string" {
+		t.Errorf("expect result_type to be a string, but got %q", s)
+	}
+	if _, err := schema.Query("$.properties.user.properties.screen_name"); err != nil {
+		t.Errorf("expect nested properties, but got %v", err)
+	}
+}
+
+// gen.go
+
+func TestGenerateStructs(t *testing.T) {
+	a, _ := Parse([]byte(`{"id": 1, "user_name": "ann", "ok": true, "tags": ["x"], "geo": {"lat": 1.5}, "urls": []}`))
+	b, _ := Parse([]byte(`{"id": 2, "user_name": null, "ok": false, "tags": [], "urls": []}`))
+
+	src, err := GenerateStructsFromSamples("item", GenOptions{Package: "model", ValidTags: true}, a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var lines []string
+	for _, line := range strings.Split(string(src), "\n") {
+		lines = append(lines, strings.Join(strings.Fields(line), " "))
+	}
+	code := strings.Join(lines, "\n")
+
+	expected := []string{
+		"package model",
+		"type Item struct {",
+		"Geo *ItemGeo `json:\"geo\"`",
+		"ID int64 `json:\"id\" valid:\"range:1,3\"`",
+		"Ok bool `json:\"ok\" valid:\"bool:both\"`",
+		"Tags []string `json:\"tags\"`",
+		"URLs []interface{} `json:\"urls\"`",
+		"UserName *string `json:\"user_name\"`",
+		"type ItemGeo struct {",
+		"Lat float64 `json:\"lat\"`",
+	}
+	for _, line := range expected {
+		if !strings.Contains(code, line) {
+			t.Errorf("expect `%s` in the source:\n%s", line, src)
+		}
+	}
+
+	if _, err := GenerateStructs("x", NewFromAny(1), GenOptions{}); err == nil {
+		t.Errorf("expect error for a schema which is not an object")
+	}
+}
+
+// stats.go
+
+func TestStats(t *testing.T) {
+	jz, _ := Parse([]byte(`{"a": [1, 2, 3], "b": {"a": "xyz", "c": [[]]}, "d": ""}`))
+
+	s := jz.Stats()
+	if s.Nodes != 10 || s.MaxDepth != 3 {
+		t.Errorf("expect 10 nodes and depth 3, but got %d nodes and depth %d", s.Nodes, s.MaxDepth)
+	}
+
+	if s.Types[JzTypeArr] != 3 || s.Types[JzTypeInt] != 3 || s.Types[JzTypeObj] != 2 || s.Types[JzTypeStr] != 2 {
+		t.Errorf("unexpected counts of types %v", s.Types)
+	}
+
+	if s.Keys["a"] != 2 || s.Keys["c"] != 1 {
+		t.Errorf("unexpected key frequency %v", s.Keys)
+	}
+
+	if len(s.StringLengths) != 3 || s.StringLengths[0] != 1 || s.StringLengths[2] != 1 {
+		t.Errorf("unexpected string lengths %v", s.StringLengths)
+	}
+
+	if s.MinNumber != 1 || s.MaxNumber != 3 || s.MinInt != 1 || s.MaxInt != 3 {
+		t.Errorf("expect numbers in [1, 3], but got [%v, %v]", s.MinNumber, s.MaxNumber)
+	}
+
+	big, _ := Parse([]byte(`[9007199254740993, -9007199254740993]`))
+	bs := big.Stats()
+	if bs.MaxInt != 9007199254740993 || bs.MinInt != -9007199254740993 {
+		t.Errorf("expect exact range of integers, but got [%d, %d]", bs.MinInt, bs.MaxInt)
+	}
+	if n := bs.Jzon().GetInt("$.numbers.max", 0); n != 9007199254740993 {
+		t.Errorf("expect max = 9007199254740993 in the report, but got %d", n)
+	}
+
+	if len(s.LargestArrays) != 3 || s.LargestArrays[0] != (ArrayStats{"$.a", 3}) {
+		t.Errorf("unexpected largest arrays %v", s.LargestArrays)
+	}
+
+	if s.Memory <= 0 {
+		t.Errorf("expect a positive estimate of memory, but got %d", s.Memory)
+	}
+
+	report := s.Jzon()
+	cases := map[string]string{
+		"$.types.array":            "3",
+		"$.numbers.max":            "3",
+		"$.string_lengths['2-3']":  "1",
+		"$.largest_arrays[0].path": `"$.a"`,
+	}
+	for path, expected := range cases {
+		if v, err := report.Query(path); err != nil || v.Compact() != expected {
+			t.Errorf("expect %s = %s in the report %s", path, expected, report.Compact())
+		}
+	}
+}
+
+// redact.go
+
+func TestRedact(t *testing.T) {
+	jz, _ := Parse([]byte(`{"user": {"name": "ann", "Password": "p", "card": "4111111111111111", "email": "a@b.c",
+		"author": "x"}, "items": [{"id": 1, "note": "n"}, {"id": 2, "note": "m"}], "api_key": {"v": 1}}`))
+	orig := jz.DeepClone()
+
+	res := jz.Redact()
+	if !jz.Equal(orig) {
+		t.Errorf("expect the input to be intact")
+	}
+	cases := map[string]string{
+		"$.user.Password": `"***"`,
+		"$.api_key":       `"***"`,
+		"$.user.author":   `"x"`,
+		"$.user.name":     `"ann"`,
+	}
+	for path, expected := range cases {
+		if v, err := res.Query(path); err != nil || v.Compact() != expected {
+			t.Errorf("expect %s = %s by the default rules, but got %v", path, expected, v)
+		}
+	}
+
+	notes, _ := CompilePath("$.items[*].note")
+	res = jz.Redact(
+		RedactRule{Keys: []string{"EMAIL"}, Action: RedactRemove},
+		RedactRule{Keys: []string{"card"}, Action: RedactKeepLast, KeepLast: 4},
+		RedactRule{Paths: []*Path{notes}, Action: RedactHash, Salt: []byte("salt")},
+		RedactRule{Predicate: func(path Path, node *Jzon) bool {
+			return node.Type == JzTypeInt && node.data.(int64) == 2
+		}},
+	)
+
+	if _, err := res.Query("$.user.email"); err == nil {
+		t.Errorf("expect email to be removed")
+	}
+	if s := res.GetString("$.user.card", ""); s != "***1111" {
+		t.Errorf("expect ***1111, but got %s", s)
+	}
+	if s := res.GetString("$.user.Password", ""); s != "p" {
+		t.Errorf("expect default rules not to apply with given rules, but got %s", s)
+	}
+	if s := res.GetString("$.items[0].note", ""); len(s) != 64 || s == res.GetString("$.items[1].note", "") {
+		t.Errorf("expect distinct digests of notes, but got %s", s)
+	}
+	if s := res.GetString("$.items[1].id", ""); s != REDACT_MASK {
+		t.Errorf("expect id 2 to be masked by the predicate, but got %s", res.Compact())
+	}
+}
+
+// canonical.go
+
+func TestCanonical(t *testing.T) {
+	jz, err := Parse([]byte(`{"\u20ac": 1, "\r": 2, "\ufb33": 3, "1": 4, "\ud83d\ude00": 5, "\u0080": 6, "\u00f6": 7}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "{\"\\r\":2,\"1\":4,\"\u0080\":6,\"\u00f6\":7,\"\u20ac\":1,\"\U0001F600\":5,\"\ufb33\":3}"
+	if s := string(jz.Canonical()); s != expected {
+		t.Errorf("expect keys sorted by UTF-16 code units %s, but got %s", expected, s)
+	}
+
+	numbers := map[float64]string{
+		333333333.33333329: "333333333.3333333",
+		1e30:               "1e+30",
+		4.50:               "4.5",
+		2e-3:               "0.002",
+		1e-27:              "1e-27",
+		-1e-7:              "-1e-7",
+		1e21:               "1e+21",
+		1e20:               "100000000000000000000",
+	}
+	for f, expected := range numbers {
+		if s := string(NewFromAny(f).Canonical()); s != expected {
+			t.Errorf("expect %v to be %s, but got %s", f, expected, s)
+		}
+	}
+
+	if s := string(NewFromAny(math.Copysign(0, -1)).Canonical()); s != "0" {
+		t.Errorf("expect negative zero to be 0, but got %s", s)
+	}
+
+	if s := string(NewFromAny(int64(1) << 53).Canonical()); s != "9007199254740992" {
+		t.Errorf("expect integers formatted as numbers, but got %s", s)
+	}
+
+	str := NewFromAny("\u20ac$\x0f\nA'B\"\\/<")
+	if s := string(str.Canonical()); s != `"€$\u000f\nA'B\"\\/<"` {
+		t.Errorf("expect minimal escaping, but got %s", s)
+	}
+
+	a, _ := Parse([]byte(`{"b": [1, 2.0, true, null], "a": "x"}`))
+	b, _ := Parse([]byte(`{"a": "x", "b": [1.0, 2, true, null]}`))
+	if string(a.Canonical()) != `{"a":"x","b":[1,2,true,null]}` {
+		t.Errorf("unexpected canonical form %s", a.Canonical())
+	}
+
+	if !bytes.Equal(a.Hash(sha256.New()), b.Hash(sha256.New())) {
+		t.Errorf("expect equal hashes of equivalent documents")
+	}
+
+	sum := sha256.Sum256(a.Canonical())
+	if !bytes.Equal(a.Hash(sha256.New()), sum[:]) {
+		t.Errorf("expect the hash of the canonical form")
+	}
+	if s, err := a.CanonicalStrict(); err != nil || !bytes.Equal(s, a.Canonical()) {
+		t.Errorf("expect the strict form equal to the canonical form, but got %s (%v)", s, err)
+	}
+
+	for _, n := range []int64{MAX_SAFE_INTEGER + 1, -MAX_SAFE_INTEGER - 1, 1<<53 + 1} {
+		big, _ := Parse([]byte(fmt.Sprintf(`{"a": [0, {"b": %d}]}`, n)))
+		if _, err := big.CanonicalStrict(); err == nil || !strings.Contains(err.Error(), "$.a[1].b") {
+			t.Errorf("expect error at $.a[1].b for %d, but got %v", n, err)
+		}
+	}
+	for _, n := range []int64{MAX_SAFE_INTEGER, -MAX_SAFE_INTEGER} {
+		if _, err := NewFromAny(n).CanonicalStrict(); err != nil {
+			t.Errorf("expect %d to be I-JSON, but got %v", n, err)
+		}
+	}
+}
+
+// sign.go
+
+func TestSign(t *testing.T) {
+	a, _ := Parse([]byte(`{"event": "push", "id": 42, "repo": {"name": "jzon", "stars": 1.0}}`))
+	b, _ := Parse([]byte(`{"repo": {"stars": 1, "name": "jzon"}, "id": 42, "event": "push"}`))
+	secret := []byte("secret")
+
+	sig, err := Sign(a, secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := Verify(b, sig, secret); !ok || err != nil {
+		t.Errorf("expect the signature valid for reordered keys, but got %v, %v", ok, err)
+	}
+	if ok, _ := Verify(b, sig, []byte("other")); ok {
+		t.Errorf("expect the signature invalid for another secret")
+	}
+
+	pub, priv, _ := ed25519.GenerateKey(nil)
+	if sig, err = Sign(a, priv); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := Verify(b, sig, pub); !ok || err != nil {
+		t.Errorf("expect the Ed25519 signature valid, but got %v, %v", ok, err)
+	}
+	b.Insert("id", NewFromAny(43))
+	if ok, _ := Verify(b, sig, pub); ok {
+		t.Errorf("expect the Ed25519 signature invalid for a modified document")
+	}
+
+	opts := SignOptions{Field: "signature"}
+	if _, err = SignWith(a, secret, opts); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.ValueOf("signature"); err != nil {
+		t.Errorf("expect the signature embedded, but got %s", a.Compact())
+	}
+	if ok, err := VerifyWith(a, nil, secret, opts); !ok || err != nil {
+		t.Errorf("expect the embedded signature valid, but got %v, %v", ok, err)
+	}
+	a.Insert("event", NewFromAny("pull"))
+	if ok, _ := VerifyWith(a, nil, secret, opts); ok {
+		t.Errorf("expect the embedded signature invalid for a modified document")
+	}
+
+	if _, err := Sign(a, "secret"); err == nil {
+		t.Errorf("expect error for unsupported keys")
+	}
+	if _, err := SignWith(NewFromAny(1), secret, opts); err == nil {
+		t.Errorf("expect error for embedding a signature in a non-object")
+	}
+	// 9007199254740993 and 9007199254740992 are the same double
+	if _, err := Sign(NewFromAny(map[string]Any{"amount": int64(9007199254740993)}), secret); err == nil {
+		t.Errorf("expect error for signing an integer which is not I-JSON")
+	}
+	signed := NewFromAny(map[string]Any{"amount": int64(9007199254740991)})
+	if sig, err = Sign(signed, secret); err != nil {
+		t.Fatal(err)
+	}
+	signed.Insert("amount", NewFromAny(int64(9007199254740993)))
+	if ok, err := Verify(signed, sig, secret); ok || err == nil {
+		t.Errorf("expect error for verifying a tampered big integer, but got %v, %v", ok, err)
+	}
+	if _, err = SignWith(signed, priv, opts); err == nil {
+		t.Errorf("expect error for signing an integer which is not I-JSON with Field")
+	}
+}
+
+// crypt.go
+
+func TestEncryptPaths(t *testing.T) {
+	jz, _ := Parse([]byte(`{"user": {"name": "ann", "ssn": "123-45-6789", "geo": {"lat": 0.1234567, "n": 2.0}},
+		"cards": [{"number": 9007199254740993}, {"number": "4111"}], "amount": 1e-27}`))
+	orig := jz.DeepClone()
+
+	aead, err := NewAESGCM([]byte("0123456789abcdef"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	paths := []string{"$.user.ssn", "$.user.geo", "$.cards[*].number", "$.amount", "$.missing"}
+	if err = EncryptPaths(jz, paths, aead); err != nil {
+		t.Fatal(err)
+	}
+
+	if s := jz.GetString("$.user.ssn.ciphertext", ""); s == "" || strings.Contains(jz.Compact(), "6789") {
+		t.Errorf("expect ssn to be encrypted, but got %s", jz.Compact())
+	}
+	if s := jz.GetString("$.user.name", ""); s != "ann" {
+		t.Errorf("expect other values to be intact, but got %s", s)
+	}
+
+	other, _ := NewAESGCM([]byte("fedcba9876543210"))
+	encrypted := jz.DeepClone()
+	if err = DecryptPaths(jz, paths, other); err == nil || !jz.Equal(encrypted) {
+		t.Errorf("expect decryption with another key fails and changes nothing, but got %v", err)
+	}
+
+	if err = DecryptPaths(jz, paths, aead); err != nil {
+		t.Fatal(err)
+	}
+	if !jz.Equal(orig) {
+		t.Errorf("expect %s after decryption, but got %s", orig.Compact(), jz.Compact())
+	}
+	if n := jz.GetInt("$.cards[0].number", 0); n != 9007199254740993 {
+		t.Errorf("expect integers decrypted exactly, but got %d", n)
+	}
+
+	EncryptPaths(jz, []string{"$.user.ssn"}, aead)
+	moved, _ := jz.Query("$.user.ssn")
+	user, _ := jz.ValueOf("user")
+	user.Insert("name", moved.DeepClone())
+	if err = DecryptPaths(jz, []string{"$.user.name"}, aead); err == nil {
+		t.Errorf("expect an envelope moved to another path fails to decrypt")
+	}
+	// nodes shared with the original are replaced in the copy only
+	plain, _ := Parse([]byte(`{"user": {"ssn": "123-45-6789"}, "cards": [{"number": "4111"}]}`))
+	snapshot := plain.DeepClone()
+	copied := plain.Clone()
+	if err = EncryptPaths(copied, []string{"$.user.ssn", "$.cards[0].number"}, aead); err != nil {
+		t.Fatal(err)
+	}
+	if !plain.Equal(snapshot) {
+		t.Errorf("expect the original to be unchanged, but got %s", plain.Compact())
+	}
+	if err = DecryptPaths(copied, []string{"$.user.ssn", "$.cards[0].number"}, aead); err != nil || !copied.Equal(snapshot) {
+		t.Errorf("expect the copy to be decrypted, but got %s (%v)", copied.Compact(), err)
+	}
+
+	shared := NewFromAny("secret")
+	doc := New(JzTypeObj)
+	doc.Insert("a", shared)
+	doc.Insert("b", shared)
+	if err = EncryptPaths(doc, []string{"$.a"}, aead); err != nil {
+		t.Fatal(err)
+	}
+	if s := doc.GetString("$.b", ""); s != "secret" || shared.Type != JzTypeStr {
+		t.Errorf("expect a shared node to be replaced at $.a only, but got %s", doc.Compact())
+	}
+
+	root := NewFromAny("whole")
+	if err = EncryptPaths(root, []string{"$"}, aead); err != nil || root.Type != JzTypeObj {
+		t.Errorf("expect the root to be encrypted, but got %s (%v)", root.Compact(), err)
+	}
+	if err = DecryptPaths(root, []string{"$"}, aead); err != nil || root.Type != JzTypeStr {
+		t.Errorf("expect the root to be decrypted, but got %s (%v)", root.Compact(), err)
+	}
+}
+
+// refs.go
+
+func TestResolveRefs(t *testing.T) {
+	files := fstest.MapFS{
+		"schemas/common.json": {Data: []byte(`{"id": {"type": "integer"}, "name": {"$ref": "#/str"}, "str": {"type": "string"}}`)},
+		"schemas/tree.json":   {Data: []byte(`{"node": {"children": {"items": {"$ref": "#/node"}}}}`)},
+	}
+	loader := FSLoader{FS: files}
+
+	root, _ := Parse([]byte(`{"definitions": {"user": {"properties": {"id": {"$ref": "common.json#/id"},
+		"name": {"$ref": "common.json#/name"}, "tags": [{"$ref": "#/definitions/tag"}]}}, "tag": {"type": "string"}},
+		"user": {"$ref": "#/definitions/user"}}`))
+	orig := root.DeepClone()
+
+	res, err := ResolveRefsWith(root, loader, RefOptions{Base: "schemas/root.json"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := map[string]string{
+		"$.user.properties.id.type":      `"integer"`,
+		"$.user.properties.name.type":    `"string"`,
+		"$.user.properties.tags[0].type": `"string"`,
+	}
+	for path, expected := range cases {
+		if v, err := res.Query(path); err != nil || v.Compact() != expected {
+			t.Errorf("expect %s = %s, but got %v", path, expected, err)
+		}
+	}
+	if !root.Equal(orig) {
+		t.Errorf("expect the input to be intact")
+	}
+
+	cyclic, _ := Parse([]byte(`{"tree": {"$ref": "tree.json#/node"}}`))
+	if _, err = ResolveRefsWith(cyclic, loader, RefOptions{Base: "schemas/root.json"}); err == nil {
+		t.Errorf("expect error for cyclic references")
+	}
+
+	res, err = ResolveRefsWith(cyclic, loader, RefOptions{Base: "schemas/root.json", KeepCycles: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree, _ := res.ValueOf("tree")
+	if items, _ := tree.Query("$.children.items"); items != tree {
+		t.Errorf("expect the cyclic reference to be the shared node")
+	}
+
+	self, _ := Parse([]byte(`{"a": {"$ref": "#/b"}, "b": {"$ref": "#/a"}}`))
+	if _, err = ResolveRefsWith(self, loader, RefOptions{KeepCycles: true}); err == nil {
+		t.Errorf("expect error for references without content")
+	}
+
+	if _, err = ResolveRefs(orig, loader); err == nil {
+		t.Errorf("expect error for missing files")
+	}
+}
+
+func TestBundle(t *testing.T) {
+	files := fstest.MapFS{
+		"common.json": {Data: []byte(`{"id": {"type": "integer"}, "pair": {"items": [{"$ref": "#/id"}, {"$ref": "root.json#/x"}]}}`)},
+	}
+
+	root, _ := Parse([]byte(`{"x": {"type": "null"}, "definitions": {"common_id": {}},
+		"a": {"$ref": "common.json#/pair"}, "b": {"$ref": "common.json#/id"}, "c": {"$ref": "#/x"}}`))
+
+	res, err := BundleWith(root, FSLoader{FS: files}, RefOptions{Base: "root.json"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := map[string]string{
+		"$.a['$ref']": "#/definitions/common_pair",
+		"$.b['$ref']": "#/definitions/common_id_2",
+		"$.c['$ref']": "#/x",
+		"$.definitions.common_pair.items[0]['$ref']": "#/definitions/common_id_2",
+		"$.definitions.common_pair.items[1]['$ref']": "#/x",
+		"$.definitions.common_id_2.type":             "integer",
+	}
+	for path, expected := range cases {
+		if s := res.GetString(path, ""); s != expected {
+			t.Errorf("expect %s = %s, but got %q in %s", path, expected, s, res.Compact())
+		}
+	}
+
+	resolved, err := ResolveRefs(res, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := resolved.GetString("$.a.items[0].type", ""); s != "integer" {
+		t.Errorf("expect the bundle resolvable without the loader, but got %s", resolved.Compact())
+	}
+}
+
+// generic.go
+
+func TestGeneric(t *testing.T) {
+	type Host struct {
+		Name  string   `json:"name"`
+		Port  uint16   `json:"port"`
+		Tags  []string `json:"tags"`
+		Extra *Jzon    `json:"extra"`
+		Next  *Host    `json:"next"`
+	}
+
+	jz, _ := Parse([]byte(`{"hosts": [
+		{"name": "a", "port": 80, "tags": ["x"], "extra": {"k": 1}, "next": {"name": "b", "port": 81.0}},
+		{"name": "c", "port": 70000}
+	], "ratio": 2, "any": [1, "s", null, {"k": true}]}`))
+
+	host, err := Get[Host](jz, "$.hosts[0]")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if host.Name != "a" || host.Port != 80 || len(host.Tags) != 1 || host.Next == nil || host.Next.Port != 81 {
+		t.Errorf("unexpected host: %+v", host)
+	}
+
+	if k := host.Extra.GetInt("$.k", 0); k != 1 {
+		t.Errorf("expect *Jzon field to receive the node, but got %v", host.Extra)
+	}
+
+	if _, err = Get[Host](jz, "$.hosts[1]"); err == nil || !strings.Contains(err.Error(), "$.port") {
+		t.Errorf("expect error for overflowing uint16 at $.port, but err is %v", err)
+	}
+
+	if f, err := Get[float64](jz, "$.ratio"); err != nil || f != 2 {
+		t.Errorf("expect 2.0, but got %f (%v)", f, err)
+	}
+
+	if _, err := Get[string](jz, "$.ratio"); err == nil {
+		t.Errorf("expect error for decoding an integer into string")
+	}
+
+	if f, err := Decode[float32](NewFromAny(1e300)); err == nil {
+		t.Errorf("expect error for overflowing float32, but got %f", f)
+	}
+
+	if f, err := Decode[float32](NewFromAny(-1.5)); err != nil || f != -1.5 {
+		t.Errorf("expect -1.5, but got %f (%v)", f, err)
+	}
+
+	any, err := Get[[]Any](jz, "$.any")
+	if err != nil || len(any) != 4 || any[0] != int64(1) || any[2] != nil {
+		t.Errorf("unexpected []Any: %v (%v)", any, err)
+	}
+
+	if _, err := ArrayOf[string](jz); err == nil {
+		t.Errorf("expect error for a non-array")
+	}
+
+	hosts, err := ArrayOf[Host](NewFromAny(jz.MustArray("$.hosts")[:1]))
+	if err != nil || len(hosts) != 1 || hosts[0].Name != "a" {
+		t.Errorf("unexpected hosts: %v (%v)", hosts, err)
+	}
+
+	built := From(Host{Name: "d", Port: 8080, Next: &Host{Name: "e"}})
+	if built == nil || built.GetString("$.next.name", "") != "e" || built.GetInt("$.port", 0) != 8080 {
+		t.Errorf("unexpected node built from Host: %v", built)
+	}
+
+	if back, err := Decode[Host](built); err != nil || back.Next.Name != "e" {
+		t.Errorf("expect decoding the built node back, but got %+v (%v)", back, err)
+	}
+}
+
+// walk.go
+
+func TestWalk(t *testing.T) {
+	jz, err := Parse([]byte(`{"a": {"b": [1, 2]}, "c": 3}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var visited []string
+	jz.Walk(func(path Path, node *Jzon) WalkAction {
+		visited = append(visited, path.String())
+		return WalkContinue
+	})
+
+	if s := strings.Join(visited, " "); s != "$ $.a $.a.b $.a.b[0] $.a.b[1] $.c" {
+		t.Errorf("unexpected pre-order: %s", s)
+	}
+
+	visited = visited[:0]
+	jz.WalkPostOrder(func(path Path, node *Jzon) WalkAction {
+		visited = append(visited, path.String())
+		return WalkContinue
+	})
+
+	if s := strings.Join(visited, " "); s != "$.a.b[0] $.a.b[1] $.a.b $.a $.c $" {
+		t.Errorf("unexpected post-order: %s", s)
+	}
+
+	visited = visited[:0]
+	jz.Walk(func(path Path, node *Jzon) WalkAction {
+		visited = append(visited, path.String())
+		switch path.String() {
+		case "$.a.b":
+			return WalkSkipChildren
+		case "$.c":
+			return WalkStop
+		}
+		return WalkContinue
+	})
+
+	if s := strings.Join(visited, " "); s != "$ $.a $.a.b $.c" {
+		t.Errorf("unexpected visits with skipping: %s", s)
+	}
+}
+
+func TestWalkMut(t *testing.T) {
+	jz, err := Parse([]byte(`{"a": {"b": [1, 2, 3]}, "secret": "x"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jz.WalkMut(func(path Path, node *Jzon) (*Jzon, WalkAction) {
+		if path.String() == "$.secret" {
+			return nil, WalkDelete
+		}
+		if n, err := node.Integer(); err == nil {
+			if n == 2 {
+				return nil, WalkDelete
+			}
+			return NewFromAny(n * 10), WalkContinue
+		}
+		return nil, WalkContinue
+	})
+
+	if has, _ := jz.Has("secret"); has {
+		t.Errorf("expect secret to be deleted")
+	}
+
+	b, _ := jz.Query("$.a.b")
+	if compact := b.Compact(); compact != "[10,30]" {
+		t.Errorf("expect [10,30], but got %s", compact)
+	}
+
+	jz.WalkMut(func(path Path, node *Jzon) (*Jzon, WalkAction) {
+		return NewFromAny("root"), WalkContinue
+	})
+
+	if s, _ := jz.String(); s != "root" {
+		t.Errorf("expect root to be replaced, but got %s", jz.Compact())
+	}
+}
+
+// iter.go
+
+func TestEntries(t *testing.T) {
+	jz, err := Parse([]byte(`{"c": 3, "a": 1, "b": 2}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var keys []string
+	for k, v := range jz.Entries() {
+		keys = append(keys, k)
+		if n, _ := v.Integer(); n != int64(len(keys)) {
+			t.Errorf("expect %s = %d, but got %d", k, len(keys), n)
+		}
+		if k == "b" {
+			break
+		}
+	}
+
+	if s := strings.Join(keys, ","); s != "a,b" {
+		t.Errorf("expect a,b, but got %s", s)
+	}
+
+	for range NewFromAny(1).Entries() {
+		t.Errorf("expect nothing for a non-object")
+	}
+}
+
+func TestElements(t *testing.T) {
+	jz, err := Parse([]byte(`[10, 20, 30]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var sum int64
+	for i, v := range jz.Elements() {
+		n, _ := v.Integer()
+		sum += n
+		if i == 1 {
+			break
+		}
+	}
+
+	if sum != 30 {
+		t.Errorf("expect sum = 30, but sum is %d", sum)
+	}
+
+	for range New(JzTypeObj).Elements() {
+		t.Errorf("expect nothing for a non-array")
+	}
+}
+
+// clone.go
+
+func TestClone(t *testing.T) {
+	jz, err := Parse([]byte(`{"a": {"b": [1, 2]}, "c": "d"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	shallow := jz.Clone()
+	shallow.Delete("c")
+	if has, _ := jz.Has("c"); !has {
+		t.Errorf("expect deleting from a shallow clone leaves the original untouched")
+	}
+
+	a, _ := shallow.ValueOf("a")
+	a.Insert("e", NewFromAny(1))
+	if !jz.Search("$.a.e") {
+		t.Errorf("expect children to be shared by a shallow clone")
+	}
+
+	deep := jz.DeepClone()
+	b, _ := deep.Query("$.a.b")
+	b.Append(NewFromAny(3))
+	if l, _ := jz.Query("$.a.b"); l.Compact() != "[1,2]" {
+		t.Errorf("expect deep clone to share nothing, but original is %s", l.Compact())
+	}
+
+	if !jz.Equal(jz.DeepClone()) {
+		t.Errorf("expect a deep clone equals to the original")
+	}
+
+	if jz.Equal(deep) {
+		t.Errorf("expect the modified clone differs from the original")
+	}
+
+	if c := NewFromAny(*jz); c == nil || !c.Equal(jz) {
+		t.Errorf("expect NewFromAny(Jzon) performs as deep clone")
+	}
+
+	if c := NewFromAny(jz); c == jz || !c.Equal(jz) {
+		t.Errorf("expect NewFromAny(*Jzon) performs as shallow clone")
+	}
+}
+
+func TestEqual(t *testing.T) {
+	x, _ := Parse([]byte(`{"a": 1, "b": [true, null, "s"]}`))
+	y, _ := Parse([]byte(`{"b": [true, null, "s"], "a": 1.0}`))
+
+	if x.Equal(y) {
+		t.Errorf("expect 1 and 1.0 are different without NumericEquivalence")
+	}
+
+	if !x.EqualWith(y, EqualOptions{NumericEquivalence: true}) {
+		t.Errorf("expect 1 and 1.0 are equal with NumericEquivalence")
+	}
+
+	big, _ := Parse([]byte(`[9007199254740993, 9007199254740992.0, 9007199254740992]`))
+	bs, _ := big.Array()
+	if bs[0].EqualWith(bs[1], EqualOptions{NumericEquivalence: true}) || bs[1].EqualWith(bs[0], EqualOptions{NumericEquivalence: true}) {
+		t.Errorf("expect 9007199254740993 and 9007199254740992.0 are different with NumericEquivalence")
+	}
+	if !bs[2].EqualWith(bs[1], EqualOptions{NumericEquivalence: true}) {
+		t.Errorf("expect 9007199254740992 and 9007199254740992.0 are equal with NumericEquivalence")
+	}
+	if NewFromAny(int64(0)).EqualWith(NewFromAny(math.NaN()), EqualOptions{NumericEquivalence: true}) {
+		t.Errorf("expect NaN is not equal to any integer")
+	}
+
+	z, _ := Parse([]byte(`{"a": 1, "b": [true, "s", null]}`))
+	if x.Equal(z) {
+		t.Errorf("expect arrays in different orders are different")
+	}
+}
+
+func TestOFilter(t *testing.T) {
+	jz, _ := Parse([]byte(`{"a": 1, "b": 2, "c": 3}`))
+
+	res, err := jz.OFilter(func(k string, v *Jzon) bool { return k != "b" })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res.Insert("d", NewFromAny(4))
+	if l, _ := jz.Length(); l != 3 {
+		t.Errorf("expect the original has 3 keys, but it has %d", l)
+	}
+
+	if l, _ := res.Length(); l != 3 {
+		t.Errorf("expect the result has 3 keys, but it has %d", l)
+	}
+}
+
+// diff.go
+
+func TestDiff(t *testing.T) {
+	a, _ := Parse([]byte(`{"name": "a", "tags": ["x", "y"], "meta": {"v": 1}, "gone": true}`))
+	b, _ := Parse([]byte(`{"name": "b", "tags": ["x", "y", "z"], "meta": {"v": 2}, "new": null}`))
+
+	patch := Diff(a, b)
+	expected := "- /gone: true\n" +
+		"~ /meta/v: 1 => 2\n" +
+		"~ /name: \"a\" => \"b\"\n" +
+		"+ /tags/2: \"z\"\n" +
+		"+ /new: null\n"
+	if s := patch.String(); s != expected {
+		t.Errorf("expect patch:\n%s\nbut got:\n%s", expected, s)
+	}
+
+	doc := patch.Jzon()
+	if l, _ := doc.Length(); l != 5 {
+		t.Errorf("expect 5 operations, but got %d", l)
+	}
+
+	if op, _ := doc.Query("$[0].op"); op.Compact() != `"remove"` {
+		t.Errorf("expect the first operation is remove, but got %s", op.Compact())
+	}
+
+	if patch = Diff(a, a.DeepClone()); len(patch) != 0 {
+		t.Errorf("expect no operation for equal trees, but got %s", patch)
+	}
+}
+
+func TestDiffArrayLCS(t *testing.T) {
+	a, _ := Parse([]byte(`[1, 2, 3, 4]`))
+	b, _ := Parse([]byte(`[0, 1, 3, 4, 5]`))
+
+	if patch := Diff(a, b); len(patch) != 3 {
+		t.Errorf("expect 3 operations by index, but got:\n%s", patch)
+	}
+
+	patch := DiffWith(a, b, DiffOptions{ArrayLCS: true})
+	expected := "+ /0: 0\n- /2: 2\n+ /4: 5\n"
+	if s := patch.String(); s != expected {
+		t.Errorf("expect patch:\n%s\nbut got:\n%s", expected, s)
+	}
+}
+
+func TestDiffMoveCopy(t *testing.T) {
+	a, _ := Parse([]byte(`{"old": {"k": 1}, "keep": [1, 2]}`))
+	b, _ := Parse([]byte(`{"renamed": {"k": 1}, "keep": [1, 2], "dup": [1, 2]}`))
+
+	patch := DiffWith(a, b, DiffOptions{DetectMoves: true, DetectCopies: true})
+	expected := "& /keep => /dup\n> /old => /renamed\n"
+	if s := patch.String(); s != expected {
+		t.Errorf("expect patch:\n%s\nbut got:\n%s", expected, s)
+	}
+
+	from, _ := patch.Jzon().Query("$[1].from")
+	if s, _ := from.String(); s != "/old" {
+		t.Errorf("expect move from /old, but got %s", s)
+	}
+}
+
+func TestFormatPointer(t *testing.T) {
+	if p := formatPointer([]string{"a/b", "m~n", "0"}); p != "/a~1b/m~0n/0" {
+		t.Errorf("expect /a~1b/m~0n/0, but got %s", p)
+	}
+}
+
+// patch.go
+
+func TestApplyPatch(t *testing.T) {
+	jz, _ := Parse([]byte(`{"a": {"b": [1, 2]}, "c": "d", "m~n": {"x/y": 1}}`))
+	patch, _ := Parse([]byte(`[
+		{"op": "add", "path": "/a/b/1", "value": 9},
+		{"op": "add", "path": "/a/b/-", "value": 3},
+		{"op": "remove", "path": "/c"},
+		{"op": "replace", "path": "/m~0n/x~1y", "value": 2},
+		{"op": "copy", "from": "/a/b", "path": "/e"},
+		{"op": "move", "from": "/a/b/0", "path": "/f"},
+		{"op": "test", "path": "/f", "value": 1.0}
+	]`))
+
+	keep, _ := jz.ValueOf("a")
+	if err := jz.ApplyPatch(patch); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := map[string]string{
+		"$.a.b": "[9,2,3]",
+		"$.e":   "[1,9,2,3]",
+		"$.f":   "1",
+	}
+	for path, expected := range cases {
+		v, err := jz.Query(path)
+		if err != nil || v.Compact() != expected {
+			t.Errorf("expect %s = %s, but got %v (%v)", path, expected, v, err)
+		}
+	}
+
+	if jz.Search("$.c") {
+		t.Errorf("expect $.c to be removed")
+	}
+
+	if v, _ := jz.ValueOf("m~n"); !v.Equal(NewFromAny(map[string]*Jzon{"x/y": NewFromAny(2)})) {
+		t.Errorf("expect escaped pointer tokens, but got %s", v.Compact())
+	}
+
+	if a, _ := jz.ValueOf("a"); a != keep {
+		t.Errorf("expect untouched nodes to stay the same nodes")
+	}
+}
+
+func TestApplyPatchAtomic(t *testing.T) {
+	jz, _ := Parse([]byte(`{"a": [1, 2], "b": "c"}`))
+	original := jz.DeepClone()
+
+	patch, _ := Parse([]byte(`[
+		{"op": "remove", "path": "/b"},
+		{"op": "add", "path": "/a/5", "value": 1}
+	]`))
+
+	err := jz.ApplyPatch(patch)
+	perr, ok := err.(*PatchError)
+	if !ok {
+		t.Fatalf("expect PatchError, but err is %v", err)
+	}
+
+	if perr.Index != 1 || perr.Pointer != "/a/5" {
+		t.Errorf("expect operation 1 at /a/5 failed, but got %v", perr)
+	}
+
+	if !jz.Equal(original) {
+		t.Errorf("expect the node to be unchanged, but got %s", jz.Compact())
+	}
+
+	malformed := []string{
+		`[{"op": "remove", "path": "/a/01"}]`,
+		`[{"op": "test", "path": "/b", "value": "x"}]`,
+		`[{"op": "move", "from": "/a", "path": "/a/0"}]`,
+		`[{"op": "add", "path": "/x"}]`,
+		`[{"op": "unknown", "path": "/b"}]`,
+		`[{"op": "remove", "path": ""}]`,
+	}
+	for _, m := range malformed {
+		patch, _ := Parse([]byte(m))
+		if err := jz.ApplyPatch(patch); err == nil {
+			t.Errorf("expect error for %s", m)
+		}
+	}
+
+	bigDoc, _ := Parse([]byte(`{"n": 9007199254740993}`))
+	bigTest, _ := Parse([]byte(`[{"op": "test", "path": "/n", "value": 9007199254740992.0}]`))
+	if err := bigDoc.ApplyPatch(bigTest); err == nil {
+		t.Errorf("expect test of 9007199254740993 against 9007199254740992.0 to fail")
+	}
+
+	a, _ := Parse([]byte(`{"x": [1, 2, 3, 4], "y": {"k": "v"}, "z": 1}`))
+	b, _ := Parse([]byte(`{"x": [0, 2, 4, 5], "y": {"k": "w", "n": [1]}, "r": 1}`))
+	for _, opts := range []DiffOptions{{}, {ArrayLCS: true, DetectMoves: true, DetectCopies: true}} {
+		c := a.DeepClone()
+		if err := DiffWith(a, b, opts).Apply(c); err != nil || !c.Equal(b) {
+			t.Errorf("expect diff to be applied with %+v, but got %s (%v)", opts, c.Compact(), err)
+		}
+	}
+}
+
+// merge.go
+
+func TestMergePatch(t *testing.T) {
+	target, _ := Parse([]byte(`{"a": "b", "c": {"d": "e", "f": "g"}, "h": [1]}`))
+	patch, _ := Parse([]byte(`{"a": "z", "c": {"f": null}, "h": {"i": 1}}`))
+	expected, _ := Parse([]byte(`{"a": "z", "c": {"d": "e"}, "h": {"i": 1}}`))
+
+	res := MergePatch(target, patch)
+	if !res.Equal(expected) {
+		t.Errorf("expect %s, but got %s", expected.Compact(), res.Compact())
+	}
+
+	if s, _ := target.Query("$.c.f"); s == nil {
+		t.Errorf("expect the target to be unchanged")
+	}
+
+	created := CreateMergePatch(target, expected)
+	if !MergePatch(target, created).Equal(expected) {
+		t.Errorf("expect the created patch %s turns target into expected", created.Compact())
+	}
+
+	if f, _ := created.Query("$.c.f"); f == nil || !f.IsNull() {
+		t.Errorf("expect removed key to be null in the created patch, but got %s", created.Compact())
+	}
+
+	if res = MergePatch(target, NewFromAny("s")); res.Compact() != `"s"` {
+		t.Errorf("expect a non-object patch replaces target, but got %s", res.Compact())
+	}
+}
+
+func TestDeepMerge(t *testing.T) {
+	defaults, _ := Parse([]byte(`{"port": 80, "hosts": [{"name": "a", "w": 1}, {"name": "b", "w": 1}], "tls": {"on": false}}`))
+	overrides, _ := Parse([]byte(`{"port": 8080.0, "hosts": [{"name": "b", "w": 5}, {"name": "c"}], "tls": {"cert": "x"}}`))
+
+	cases := []struct {
+		arrays   ArrayStrategy
+		expected string
+	}{
+		{ArrayReplace, `[{"name": "b", "w": 5}, {"name": "c"}]`},
+		{ArrayAppend, `[{"name": "a", "w": 1}, {"name": "b", "w": 1}, {"name": "b", "w": 5}, {"name": "c"}]`},
+		{ArrayMergeByIndex, `[{"name": "b", "w": 5}, {"name": "c", "w": 1}]`},
+		{ArrayMergeByKey, `[{"name": "a", "w": 1}, {"name": "b", "w": 5}, {"name": "c"}]`},
+	}
+
+	for _, c := range cases {
+		res, err := DeepMerge(defaults, overrides, MergeOptions{Arrays: c.arrays, KeyField: "name"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		hosts, _ := res.ValueOf("hosts")
+		expected, _ := Parse([]byte(c.expected))
+		if !hosts.Equal(expected) {
+			t.Errorf("expect hosts = %s with strategy %d, but got %s", c.expected, c.arrays, hosts.Compact())
+		}
+
+		if tls, _ := res.ValueOf("tls"); !tls.Search("$.on") || !tls.Search("$.cert") {
+			t.Errorf("expect objects to be merged, but got %s", tls.Compact())
+		}
+	}
+
+	conflict, _ := Parse([]byte(`{"tls": "off"}`))
+	if _, err := DeepMerge(defaults, conflict, MergeOptions{}); err != nil {
+		t.Errorf("expect conflicts to be overridden, but err is %v", err)
+	}
+
+	_, err := DeepMerge(defaults, conflict, MergeOptions{Conflicts: ConflictError})
+	if perr, ok := err.(*PathError); !ok || perr.Path != "$.tls" {
+		t.Errorf("expect conflict at $.tls, but err is %v", err)
+	}
+}
+
+// utilities.go
+func TestCompact(t *testing.T) {
+	jz, err := Parse([]byte(deepJSON))
+	if err != nil {
+		t.Error(err)
+	}
+
+	fmt.Print(jz.Compact())
+}
+
+func TestFormat(t *testing.T) {
+	jz, err := Parse([]byte(deepJSON))
+	if err != nil {
+		t.Error(err)
+	}
+
+	fmt.Println()
+	fmt.Println()
+	fmt.Print(jz.Format(0, 2))
+	fmt.Println()
+	fmt.Println()
+}
+
+func TestColoring(t *testing.T) {
+	jz, err := Parse([]byte(deepJSON))
+	if err != nil {
+		t.Error(err)
+	}
+
+	jz.Coloring(os.Stdout)
+	fmt.Println()
+}
+
+// reflect.go
+type User struct {
+	Name     string         `jzon:"name"`
+	Pwd      string         `jzon:"pwd"`
+	Model    int            `jzon:"model"`
+	EmptyTag int            `jzon:","`
+	Nest     Nested         `jzon:"nested"`
+	Array    []int          `jzon:"array"`
+	Map      map[string]int `jzon:"map"`
+	NilSlice []byte         `jzon:","`
+	Ptr      *int           `jzon:"ptr"`
+	Bool     bool           `jzon:"bool"`
+	NoTag    int
+}
+
+type ImpInterface int
+
+func (i ImpInterface) SerializeJzon() string {
+	return "INT" + strconv.Itoa(int(i))
+}
+
+type Nested struct {
+	Inner string `jzon:"inner"`
+}
+
+func TestSerialize(t *testing.T) {
+	user := User{
+		Name:  "ZuoXinyu",
+		Pwd:   "password",
+		Model: 1,
+		Nest:  Nested{Inner: "innerString"},
+		Array: []int{1, 2, 3, 4, 5},
+		Map: map[string]int{
+			"one":   1,
+			"two":   2,
+			"three": 3,
+			"four":  4,
+		},
+	}
+	jz, err := Serialize(user)
+	if err != nil {
+		t.Error(err)
+	}
+
+	jz.Print()
+	fmt.Printf("\n")
+
+	m := map[ImpInterface]int{
+		4: 1,
+		3: 2,
+		2: 1,
+	}
+
+	jz1, err := Serialize(m)
+	if err != nil {
+		t.Error(err)
+	}
+
+	jz1.Print()
+	fmt.Printf("\n")
+
+	type node struct {
+		Val  Any   `json:"val"`
+		Size uint  `json:"size"`
+		Next *node `json:"next"`
+	}
+
+	shared := &node{Val: "leaf", Size: 1}
+	jz2, err := Serialize([]*node{{Val: []Any{uint8(1), 2.5}, Size: 2, Next: shared}, shared})
+	if err != nil {
+		t.Error(err)
+	}
+	if s := string(jz2.Canonical()); s != `[{"next":{"next":null,"size":1,"val":"leaf"},"size":2,"val":[1,2.5]},{"next":null,"size":1,"val":"leaf"}]` {
+		t.Errorf("expect pointers and interfaces to be serialized, but got %s", s)
+	}
+
+	if _, err = Serialize(node{Size: math.MaxUint64}); err == nil {
+		t.Errorf("expect error for an unsigned integer overflowing int64")
+	}
+
+	cyclic := &node{Val: "loop"}
+	cyclic.Next = cyclic
+	if _, err = Serialize(cyclic); err == nil {
+		t.Errorf("expect error for a cyclic pointer")
+	}
+
+	m1 := map[string]Any{}
+	m1["self"] = []Any{m1}
+	if _, err = Serialize(m1); err == nil {
+		t.Errorf("expect error for a cyclic map")
+	}
+	type hidden struct {
+		Name  string `json:"name"`
+		node  *Jzon
+		value Jzon
+	}
+
+	for _, h := range []hidden{{Name: "nil"}, {Name: "set", node: NewFromAny(1), value: *NewFromAny(2)}} {
+		jz3, err := Serialize(h)
+		if err != nil {
+			t.Errorf("expect unexported nodes to be serialized, but got %v", err)
+			continue
+		}
+		if s := string(jz3.Canonical()); s != `{"name":"`+h.Name+`"}` {
+			t.Errorf("expect unexported nodes to be skipped, but got %s", s)
+		}
+	}
 }
 
 func TestDeserialize(t *testing.T) {
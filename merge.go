@@ -0,0 +1,225 @@
+package jzon
+
+import "fmt"
+
+// MergePatch applies a JSON Merge Patch (RFC 7396) to target, and returns the
+// merged result, a null member in the patch removes the key from target, and a
+// patch which is not an object replaces target entirely. neither target nor
+// patch is modified, and the result shares no node with them
+func MergePatch(target, patch *Jzon) *Jzon {
+	if patch.Type != JzTypeObj {
+		return patch.DeepClone()
+	}
+
+	var res = New(JzTypeObj)
+	var tm map[string]*Jzon
+	if target != nil && target.Type == JzTypeObj {
+		tm = target.data.(map[string]*Jzon)
+	}
+
+	m, pm := res.data.(map[string]*Jzon), patch.data.(map[string]*Jzon)
+	for k, v := range tm {
+		if _, ok := pm[k]; !ok {
+			m[k] = v.DeepClone()
+		}
+	}
+
+	for k, v := range pm {
+		if !v.IsNull() {
+			m[k] = MergePatch(tm[k], v)
+		}
+	}
+
+	return res
+}
+
+// CreateMergePatch returns a JSON Merge Patch which turns original into
+// modified by `MergePatch()`. since null means deletion in a merge patch,
+// a member changed to null is deleted instead when the patch is applied
+func CreateMergePatch(original, modified *Jzon) *Jzon {
+	if original.Type != JzTypeObj || modified.Type != JzTypeObj {
+		return modified.DeepClone()
+	}
+
+	var patch = New(JzTypeObj)
+	om, mm := original.data.(map[string]*Jzon), modified.data.(map[string]*Jzon)
+
+	for k := range om {
+		if _, ok := mm[k]; !ok {
+			patch.Insert(k, New(JzTypeNul))
+		}
+	}
+
+	for k, mv := range mm {
+		ov, ok := om[k]
+		switch {
+		case !ok:
+			patch.Insert(k, mv.DeepClone())
+		case ov.Equal(mv):
+		case ov.Type == JzTypeObj && mv.Type == JzTypeObj:
+			patch.Insert(k, CreateMergePatch(ov, mv))
+		default:
+			patch.Insert(k, mv.DeepClone())
+		}
+	}
+
+	return patch
+}
+
+// ArrayStrategy tells `DeepMerge()` how to merge two arrays
+type ArrayStrategy int
+
+const (
+	// ArrayReplace replaces the array in dst with the one in src
+	ArrayReplace ArrayStrategy = iota
+	// ArrayAppend appends elements of src after elements of dst
+	ArrayAppend
+	// ArrayMergeByIndex merges elements at the same index, extra elements are appended
+	ArrayMergeByIndex
+	// ArrayMergeByKey merges objects which have the same value for MergeOptions.KeyField,
+	// other elements of src are appended
+	ArrayMergeByKey
+)
+
+// ConflictStrategy tells `DeepMerge()` what to do with values of different types
+type ConflictStrategy int
+
+const (
+	// ConflictOverride replaces the value in dst with the one in src
+	ConflictOverride ConflictStrategy = iota
+	// ConflictError stops merging with an error
+	ConflictError
+)
+
+// MergeOptions controls how `DeepMerge()` merges two trees
+type MergeOptions struct {
+	Arrays    ArrayStrategy
+	KeyField  string // the member identifying objects for ArrayMergeByKey
+	Conflicts ConflictStrategy
+}
+
+// DeepMerge merges src into dst recursively and returns the merged result,
+// objects are merged key by key, arrays are merged by opts.Arrays, and other
+// values in src override those in dst. integers and floats are both numbers,
+// null never conflicts, and values of other different types conflict, see
+// opts.Conflicts. neither dst nor src is modified, and the result shares no
+// node with them
+func DeepMerge(dst, src *Jzon, opts MergeOptions) (res *Jzon, err error) {
+	return deepMerge(nil, dst, src, opts)
+}
+
+func deepMerge(segs []segment, dst, src *Jzon, opts MergeOptions) (res *Jzon, err error) {
+	if opts.Conflicts == ConflictError && !sameKind(dst, src) {
+		var seg = "$"
+		if len(segs) > 0 {
+			seg = segs[len(segs)-1].String()
+		}
+
+		return nil, &PathError{
+			Path:    (&Path{segs: segs}).String(),
+			Offset:  -1,
+			Segment: seg,
+			Depth:   len(segs),
+			Msg:     fmt.Sprintf("can not merge %s into %s", typeStrings[src.Type], typeStrings[dst.Type]),
+		}
+	}
+
+	switch {
+	case dst.Type == JzTypeObj && src.Type == JzTypeObj:
+		res = New(JzTypeObj)
+		m := res.data.(map[string]*Jzon)
+		dm, sm := dst.data.(map[string]*Jzon), src.data.(map[string]*Jzon)
+		for k, dv := range dm {
+			if _, ok := sm[k]; !ok {
+				m[k] = dv.DeepClone()
+			}
+		}
+
+		for _, k := range src.sortedKeys() {
+			dv, ok := dm[k]
+			if !ok {
+				m[k] = sm[k].DeepClone()
+				continue
+			}
+
+			if m[k], err = deepMerge(append(segs, segment{kind: _SegKey, key: k}), dv, sm[k], opts); err != nil {
+				return nil, err
+			}
+		}
+		return res, nil
+
+	case dst.Type == JzTypeArr && src.Type == JzTypeArr:
+		return mergeArrays(segs, dst.data.([]*Jzon), src.data.([]*Jzon), opts)
+	}
+
+	return src.DeepClone(), nil
+}
+
+// sameKind reports whether a and b don't conflict when merging, that is, they
+// are of the same type, or both numbers, or either of them is null
+func sameKind(a, b *Jzon) bool {
+	return a.Type == b.Type || isNumber(a) && isNumber(b) || a.IsNull() || b.IsNull()
+}
+
+func mergeArrays(segs []segment, dst, src []*Jzon, opts MergeOptions) (res *Jzon, err error) {
+	res = New(JzTypeArr)
+	for _, v := range dst {
+		res.Append(v.DeepClone())
+	}
+	arr := res.data.([]*Jzon)
+
+	switch opts.Arrays {
+	case ArrayAppend:
+
+	case ArrayMergeByIndex:
+		for i := 0; i < len(src) && i < len(arr); i++ {
+			if arr[i], err = deepMerge(append(segs, segment{kind: _SegIndex, index: i}), arr[i], src[i], opts); err != nil {
+				return nil, err
+			}
+		}
+		if len(src) <= len(arr) {
+			return res, nil
+		}
+		src = src[len(arr):]
+
+	case ArrayMergeByKey:
+		var unmatched []*Jzon
+		for _, sv := range src {
+			i := indexByKey(arr, sv, opts.KeyField)
+			if i < 0 {
+				unmatched = append(unmatched, sv)
+				continue
+			}
+			if arr[i], err = deepMerge(append(segs, segment{kind: _SegIndex, index: i}), arr[i], sv, opts); err != nil {
+				return nil, err
+			}
+		}
+		src = unmatched
+
+	default:
+		arr = arr[:0]
+	}
+
+	for _, v := range src {
+		arr = append(arr, v.DeepClone())
+	}
+	res.data = arr
+
+	return res, nil
+}
+
+// indexByKey finds the object in arr which has the same value for the key as v
+func indexByKey(arr []*Jzon, v *Jzon, key string) int {
+	kv, err := v.ValueOf(key)
+	if err != nil {
+		return -1
+	}
+
+	for i, elem := range arr {
+		if ev, err := elem.ValueOf(key); err == nil && ev.EqualWith(kv, EqualOptions{NumericEquivalence: true}) {
+			return i
+		}
+	}
+
+	return -1
+}
@@ -2,6 +2,7 @@ package jzon
 
 import (
 	"fmt"
+	"strconv"
 )
 
 type position struct {
@@ -128,6 +129,9 @@ func expectCodePoint() error {
 func trimWhiteSpaces(str []byte) []byte {
 	for {
 		switch {
+		case len(str) == 0:
+			return str
+
 		case len(str) > 1 && str[0] == '\r' && str[1] == '\n':
 			pos.row += 1
 			pos.col = 0
@@ -464,12 +468,12 @@ func parseUnicode(json []byte) (parsed []byte, rem []byte, err error) {
 			return
 		}
 
-		if isInvalidCodePoint(uc2) {
+		if !(0xDC00 <= uc2 && uc2 <= 0xDFFF) {
 			err = expectCodePoint()
 			return
 		}
 
-		uc = 0x10000 + (((uc&0x3FF)<<10 | uc2) & 0x3FF)
+		uc = 0x10000 + ((uc&0x3FF)<<10 | uc2&0x3FF)
 	}
 
 	var nBytes int
@@ -559,7 +563,7 @@ func parseNumeric(json []byte) (n int64, f float64, isInt bool, rem []byte, err
 		}
 		pos.col++
 		n, f, isInt, rem, err = parseNumeric(rem[1:])
-		n = -n
+		n, f = -n, -f
 		return
 	}
 
@@ -570,6 +574,7 @@ func parseNumeric(json []byte) (n int64, f float64, isInt bool, rem []byte, err
 				f = 0
 			} else {
 				n = 0
+				f, err = parseFloatLexeme(json, rem)
 			}
 			return
 		case rem[0] == '0' && st.match(_nStart):
@@ -625,6 +630,7 @@ func parseNumeric(json []byte) (n int64, f float64, isInt bool, rem []byte, err
 				f = 0
 			} else {
 				n = 0
+				f, err = parseFloatLexeme(json, rem)
 			}
 			return
 		default:
@@ -639,3 +645,14 @@ func parseNumeric(json []byte) (n int64, f float64, isInt bool, rem []byte, err
 		pos.col++
 	}
 }
+
+// parseFloatLexeme converts the validated number before rem to the nearest float,
+// instead of the digits accumulated by the state machine, which lose precision.
+// if the number overflows a float, such as 1e400, an error will be thrown out
+func parseFloatLexeme(json []byte, rem []byte) (f float64, err error) {
+	lexeme := string(json[:len(json)-len(rem)])
+	if f, err = strconv.ParseFloat(lexeme, 64); err != nil {
+		return 0, fmt.Errorf("number %s is out of range at [%d:%d]", lexeme, pos.row+1, pos.col+1)
+	}
+	return
+}
@@ -0,0 +1,234 @@
+package jzon
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// PatchError is the error thrown out at parsing or applying a JSON Patch,
+// Index is the index of the failing operation in the patch
+type PatchError struct {
+	Index   int
+	Op      string
+	Pointer string
+	Msg     string
+}
+
+func (e *PatchError) Error() string {
+	return fmt.Sprintf("patch operation %d (%s `%s`) failed: %s", e.Index, e.Op, e.Pointer, e.Msg)
+}
+
+// ParsePatch converts a JSON Patch document, an array of operation objects,
+// to a Patch, if any operation is malformed, an error will be thrown out
+func ParsePatch(doc *Jzon) (p Patch, err error) {
+	arr, err := doc.Array()
+	if err != nil {
+		return
+	}
+
+	p = make(Patch, 0, len(arr))
+	for i, elem := range arr {
+		var op PatchOp
+		if op, err = parsePatchOp(elem); err != nil {
+			return nil, &PatchError{Index: i, Op: op.Op, Pointer: op.Path, Msg: err.Error()}
+		}
+		p = append(p, op)
+	}
+
+	return p, nil
+}
+
+func parsePatchOp(elem *Jzon) (op PatchOp, err error) {
+	if elem.Type != JzTypeObj {
+		return op, expectTypeOf(JzTypeObj, elem.Type)
+	}
+
+	member := func(k string) (s string, err error) {
+		v, err := elem.ValueOf(k)
+		if err != nil {
+			return "", fmt.Errorf("missing member `%s`", k)
+		}
+		return v.String()
+	}
+
+	if op.Op, err = member("op"); err != nil {
+		return
+	}
+
+	if op.Path, err = member("path"); err != nil {
+		return
+	}
+
+	switch op.Op {
+	case PATCH_ADD, PATCH_REPLACE, PATCH_TEST:
+		if op.Value, err = elem.ValueOf("value"); err != nil {
+			return op, errors.New("missing member `value`")
+		}
+
+	case PATCH_MOVE, PATCH_COPY:
+		op.From, err = member("from")
+
+	case PATCH_REMOVE:
+
+	default:
+		err = fmt.Errorf("unknown operation `%s`", op.Op)
+	}
+
+	return
+}
+
+// ApplyPatch applies a JSON Patch document (RFC 6902) to the node, the patch
+// is all-or-nothing: if any operation fails, the node is left unchanged, and
+// a PatchError naming the failing operation will be thrown out
+func (jz *Jzon) ApplyPatch(patch *Jzon) (err error) {
+	p, err := ParsePatch(patch)
+	if err != nil {
+		return
+	}
+
+	return p.Apply(jz)
+}
+
+// Apply applies the patch to jz atomically, as `Jzon.ApplyPatch()` does.
+// operations are tried on a deep clone first, so nodes which are not touched
+// by the patch stay the same nodes after applying
+func (p Patch) Apply(jz *Jzon) (err error) {
+	if err = p.apply(jz.DeepClone()); err != nil {
+		return
+	}
+
+	return p.apply(jz)
+}
+
+func (p Patch) apply(doc *Jzon) (err error) {
+	for i, op := range p {
+		if err = op.apply(doc); err != nil {
+			return &PatchError{Index: i, Op: op.Op, Pointer: op.Path, Msg: err.Error()}
+		}
+	}
+
+	return nil
+}
+
+func (op PatchOp) apply(doc *Jzon) (err error) {
+	path, err := parsePointer(op.Path)
+	if err != nil {
+		return
+	}
+
+	var from []string
+	if op.Op == PATCH_MOVE || op.Op == PATCH_COPY {
+		if from, err = parsePointer(op.From); err != nil {
+			return
+		}
+	}
+
+	switch op.Op {
+	case PATCH_ADD:
+		return addPointer(doc, path, op.Value.DeepClone())
+
+	case PATCH_REMOVE:
+		_, err = removePointer(doc, path)
+		return
+
+	case PATCH_REPLACE:
+		if _, err = doc.resolvePointer(path); err != nil {
+			return
+		}
+		if len(path) == 0 {
+			*doc = *op.Value.DeepClone()
+			return nil
+		}
+		if _, err = removePointer(doc, path); err != nil {
+			return
+		}
+		return addPointer(doc, path, op.Value.DeepClone())
+
+	case PATCH_MOVE:
+		if op.From == op.Path {
+			_, err = doc.resolvePointer(from)
+			return
+		}
+		if strings.HasPrefix(op.Path, op.From+"/") {
+			return errors.New("can not move a node into one of its children")
+		}
+		var v *Jzon
+		if v, err = removePointer(doc, from); err != nil {
+			return
+		}
+		return addPointer(doc, path, v)
+
+	case PATCH_COPY:
+		var v *Jzon
+		if v, err = doc.resolvePointer(from); err != nil {
+			return
+		}
+		return addPointer(doc, path, v.DeepClone())
+
+	case PATCH_TEST:
+		var v *Jzon
+		if v, err = doc.resolvePointer(path); err != nil {
+			return
+		}
+		if !v.EqualWith(op.Value, EqualOptions{NumericEquivalence: true}) {
+			return fmt.Errorf("expect %s, but found %s", op.Value.Compact(), v.Compact())
+		}
+		return nil
+	}
+
+	return fmt.Errorf("unknown operation `%s`", op.Op)
+}
+
+// addPointer adds v at the path, a member of an object is inserted or
+// replaced, and v is inserted before the element at the index of an array
+func addPointer(doc *Jzon, path []string, v *Jzon) (err error) {
+	if len(path) == 0 {
+		*doc = *v
+		return nil
+	}
+
+	parent, err := doc.resolvePointer(path[:len(path)-1])
+	if err != nil {
+		return
+	}
+
+	last := path[len(path)-1]
+	switch parent.Type {
+	case JzTypeObj:
+		return parent.Insert(last, v)
+
+	case JzTypeArr:
+		i, err := pointerIndex(last, len(parent.data.([]*Jzon)), true)
+		if err != nil {
+			return err
+		}
+		return parent.InsertAt(i, v)
+	}
+
+	return fmt.Errorf("expect an object or an array, but found %s", typeStrings[parent.Type])
+}
+
+// removePointer removes the node at the path, and returns the removed node
+func removePointer(doc *Jzon, path []string) (v *Jzon, err error) {
+	if len(path) == 0 {
+		return nil, errors.New("can not remove the root node")
+	}
+
+	parent, err := doc.resolvePointer(path[:len(path)-1])
+	if err != nil {
+		return
+	}
+
+	if v, err = parent.resolvePointer(path[len(path)-1:]); err != nil {
+		return
+	}
+
+	last := path[len(path)-1]
+	if parent.Type == JzTypeObj {
+		return v, parent.Delete(last)
+	}
+
+	i, _ := pointerIndex(last, len(parent.data.([]*Jzon)), false)
+	return v, parent.Remove(i)
+}
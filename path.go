@@ -0,0 +1,459 @@
+package jzon
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// segKind indicates the kind of a path segment
+type segKind int
+
+const (
+	_SegKey      segKind = iota // .key
+	_SegIndex                   // [1]
+	_SegWildcard                // [*]
+)
+
+// segment is a single step of a parsed path
+type segment struct {
+	kind  segKind
+	key   string
+	index int
+}
+
+func (seg segment) String() string {
+	switch seg.kind {
+	case _SegIndex:
+		return "[" + strconv.Itoa(seg.index) + "]"
+	case _SegWildcard:
+		return "[*]"
+	case _SegKey:
+		if seg.key == "" {
+			return "['']"
+		}
+	}
+
+	return "." + escapePathKey(seg.key)
+}
+
+// escapePathKey is the reverse operation of `parsePathKey()`
+func escapePathKey(k string) string {
+	var escaped = make([]byte, 0, len(k))
+	for i := 0; i < len(k); i++ {
+		switch k[i] {
+		case '.', '[', ']', ';', '\\':
+			escaped = append(escaped, '\\', k[i])
+		default:
+			escaped = append(escaped, k[i])
+		}
+	}
+
+	return string(escaped)
+}
+
+// Path is a compiled path which can be evaluated on any Jzon node repeatedly
+// without parsing the path string again. a Path is never modified after it's
+// built, so it's safe to share a Path between goroutines
+type Path struct {
+	segs []segment
+}
+
+// CompilePath parses the path string in the grammar of `Query()` to a Path,
+// if the path string is malformed, an error will be thrown out
+func CompilePath(path string) (p *Path, err error) {
+	segs, err := parsePath([]byte(path))
+	if err != nil {
+		return
+	}
+
+	return &Path{segs: segs}, nil
+}
+
+// Root returns the root path `$`, which is the beginning of building a path
+func Root() *Path {
+	return &Path{}
+}
+
+// Key returns a new path which selects the key k in the object at this path
+func (p *Path) Key(k string) *Path {
+	return p.with(segment{kind: _SegKey, key: k})
+}
+
+// Index returns a new path which selects the index i in the array at this path,
+// a negative index selects nothing, so getting or setting it fails
+func (p *Path) Index(i int) *Path {
+	return p.with(segment{kind: _SegIndex, index: i})
+}
+
+// Wildcard returns a new path which selects every child of the node at this path
+func (p *Path) Wildcard() *Path {
+	return p.with(segment{kind: _SegWildcard})
+}
+
+func (p *Path) with(seg segment) *Path {
+	segs := make([]segment, len(p.segs), len(p.segs)+1)
+	copy(segs, p.segs)
+	return &Path{segs: append(segs, seg)}
+}
+
+// String returns the canonical path string, which can be compiled again
+func (p *Path) String() string {
+	var sb strings.Builder
+	sb.WriteByte('$')
+	for _, seg := range p.segs {
+		sb.WriteString(seg.String())
+	}
+
+	return sb.String()
+}
+
+// IsDefinite reports whether the path selects at most one node
+func (p *Path) IsDefinite() bool {
+	for _, seg := range p.segs {
+		if seg.kind == _SegWildcard {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Get returns the node at this path, if the node doesn't exist or
+// the path contains wildcards, an error will be thrown out
+func (p *Path) Get(jz *Jzon) (g *Jzon, err error) {
+	if !p.IsDefinite() {
+		return nil, expectDefinite(p)
+	}
+
+	return jz.lookup(p.segs)
+}
+
+// GetAll returns all nodes matching this path, wildcards are expanded to
+// the elements of arrays and the values of objects in the order of sorted
+// keys. those branches which don't have the selected children are skipped
+func (p *Path) GetAll(jz *Jzon) (res []*Jzon) {
+	res = []*Jzon{jz}
+	for _, seg := range p.segs {
+		var next []*Jzon
+		for _, node := range res {
+			if seg.kind == _SegWildcard {
+				next = append(next, node.children()...)
+				continue
+			}
+
+			child, err := node.child(seg)
+			if err == nil {
+				next = append(next, child)
+			}
+		}
+		res = next
+	}
+
+	return res
+}
+
+// Match is a node matched by a path, along with where it comes from. for the root
+// node Parent is nil, Key is empty and Index is -1. for an element of an array
+// Key is empty, and for a value of an object Index is -1
+type Match struct {
+	Node   *Jzon
+	Parent *Jzon
+	Key    string
+	Index  int
+	Path   string
+}
+
+// Matches performs as `GetAll()`, but it also reports the parent, the key
+// or index and the normalized path in the form of `$['a'][0]` of each match
+func (p *Path) Matches(jz *Jzon) (res []Match) {
+	type partial struct {
+		match Match
+		segs  []segment
+	}
+
+	var curr = []partial{{match: Match{Node: jz, Index: -1}}}
+	for _, seg := range p.segs {
+		var next []partial
+		for _, pm := range curr {
+			node := pm.match.Node
+			var concrete []segment
+
+			switch {
+			case seg.kind == _SegWildcard && node.Type == JzTypeArr:
+				for i := range node.data.([]*Jzon) {
+					concrete = append(concrete, segment{kind: _SegIndex, index: i})
+				}
+			case seg.kind == _SegWildcard && node.Type == JzTypeObj:
+				for _, k := range node.sortedKeys() {
+					concrete = append(concrete, segment{kind: _SegKey, key: k})
+				}
+			case seg.kind != _SegWildcard:
+				concrete = append(concrete, seg)
+			}
+
+			for _, cs := range concrete {
+				child, err := node.child(cs)
+				if err != nil {
+					continue
+				}
+
+				m := Match{Node: child, Parent: node, Index: -1}
+				if cs.kind == _SegIndex {
+					m.Index = cs.index
+				} else {
+					m.Key = cs.key
+				}
+
+				segs := make([]segment, len(pm.segs), len(pm.segs)+1)
+				copy(segs, pm.segs)
+				next = append(next, partial{match: m, segs: append(segs, cs)})
+			}
+		}
+		curr = next
+	}
+
+	for _, pm := range curr {
+		pm.match.Path = normalizeSegments(pm.segs)
+		res = append(res, pm.match)
+	}
+
+	return res
+}
+
+// Normalized returns the path in the normalized form, where every key is
+// quoted in brackets with single quotes, such as `$['key'][0][*]`, and
+// it can be compiled by `CompilePath()` again
+func (p *Path) Normalized() string {
+	return normalizeSegments(p.segs)
+}
+
+func normalizeSegments(segs []segment) string {
+	var sb strings.Builder
+	sb.WriteByte('$')
+	for _, seg := range segs {
+		switch seg.kind {
+		case _SegKey:
+			sb.WriteString("['")
+			sb.WriteString(quotePathKey(seg.key))
+			sb.WriteString("']")
+		default:
+			sb.WriteString(seg.String())
+		}
+	}
+
+	return sb.String()
+}
+
+// quotePathKey escapes the key to be surrounded with single quotes, only
+// the quote, the backslash and those control characters are escaped
+func quotePathKey(k string) string {
+	var quoted = make([]byte, 0, len(k))
+	for i := 0; i < len(k); i++ {
+		switch c := k[i]; {
+		case c == '\'', c == '\\':
+			quoted = append(quoted, '\\', c)
+		case c == '\b':
+			quoted = append(quoted, '\\', 'b')
+		case c == '\f':
+			quoted = append(quoted, '\\', 'f')
+		case c == '\n':
+			quoted = append(quoted, '\\', 'n')
+		case c == '\r':
+			quoted = append(quoted, '\\', 'r')
+		case c == '\t':
+			quoted = append(quoted, '\\', 't')
+		case c < 0x20:
+			quoted = append(quoted, fmt.Sprintf("\\u%04x", c)...)
+		default:
+			quoted = append(quoted, c)
+		}
+	}
+
+	return string(quoted)
+}
+
+// Set replaces the node at this path with v, as `Jzon.Set()` does
+func (p *Path) Set(jz *Jzon, v *Jzon) (err error) {
+	return p.set(jz, v, false)
+}
+
+// SetCreate replaces the node at this path with v, as `Jzon.SetCreate()` does
+func (p *Path) SetCreate(jz *Jzon, v *Jzon) (err error) {
+	return p.set(jz, v, true)
+}
+
+// Unset removes the node at this path, as `Jzon.Unset()` does
+func (p *Path) Unset(jz *Jzon) (err error) {
+	if !p.IsDefinite() {
+		return expectDefinite(p)
+	}
+
+	segs := p.segs
+	if len(segs) == 0 {
+		return &PathError{Path: p.String(), Offset: -1, Segment: "$", Msg: "can not unset the root node"}
+	}
+
+	var parent = jz
+	var last = len(segs) - 1
+	for i, seg := range segs[:last] {
+		if parent, err = parent.child(seg); err != nil {
+			return expectSegment(segs, i, err)
+		}
+	}
+
+	if _, err = parent.child(segs[last]); err != nil {
+		return expectSegment(segs, last, err)
+	}
+
+	if segs[last].kind == _SegIndex {
+		return parent.Remove(segs[last].index)
+	}
+
+	return parent.Delete(segs[last].key)
+}
+
+func (p *Path) set(jz *Jzon, v *Jzon, create bool) (err error) {
+	if !p.IsDefinite() {
+		return expectDefinite(p)
+	}
+
+	segs := p.segs
+	if len(segs) == 0 {
+		*jz = *v
+		return nil
+	}
+
+	var curr = jz
+	var next *Jzon
+	var last = len(segs) - 1
+
+	for i, seg := range segs[:last] {
+		next, err = curr.child(seg)
+		if err != nil && create && curr.canHold(seg) {
+			if segs[i+1].kind == _SegIndex {
+				next = New(JzTypeArr)
+			} else {
+				next = New(JzTypeObj)
+			}
+			err = curr.setChild(seg, next, true)
+		}
+
+		if err != nil {
+			return expectSegment(segs, i, err)
+		}
+
+		curr = next
+	}
+
+	if err = curr.setChild(segs[last], v, create); err != nil {
+		return expectSegment(segs, last, err)
+	}
+
+	return nil
+}
+
+// expectSegment reports the failure on the i-th segment of segs
+func expectSegment(segs []segment, i int, err error) error {
+	return &PathError{
+		Path:    (&Path{segs: segs}).String(),
+		Offset:  -1,
+		Segment: segs[i].String(),
+		Depth:   i,
+		Msg:     err.Error(),
+	}
+}
+
+// expectDefinite reports the first wildcard of p
+func expectDefinite(p *Path) error {
+	for i, seg := range p.segs {
+		if seg.kind == _SegWildcard {
+			return expectSegment(p.segs, i, errors.New("expect a definite path, but it may match multiple nodes"))
+		}
+	}
+
+	return nil
+}
+
+func (jz *Jzon) lookup(segs []segment) (curr *Jzon, err error) {
+	curr = jz
+	for i, seg := range segs {
+		curr, err = curr.child(seg)
+		if err != nil {
+			return nil, expectSegment(segs, i, err)
+		}
+	}
+
+	return
+}
+
+func (jz *Jzon) child(seg segment) (v *Jzon, err error) {
+	if seg.kind == _SegIndex {
+		return jz.ValueAt(seg.index)
+	}
+
+	return jz.ValueOf(seg.key)
+}
+
+// children returns the elements of an array or the values of an object
+// in the order of sorted keys, for other types it returns nothing
+func (jz *Jzon) children() (res []*Jzon) {
+	switch jz.Type {
+	case JzTypeArr:
+		return jz.data.([]*Jzon)
+	case JzTypeObj:
+		m := jz.data.(map[string]*Jzon)
+		for _, k := range jz.sortedKeys() {
+			res = append(res, m[k])
+		}
+	}
+
+	return res
+}
+
+// sortedKeys returns keys of an object in ascending order
+func (jz *Jzon) sortedKeys() (ks []string) {
+	ks, _ = jz.Keys()
+	sort.Strings(ks)
+	return ks
+}
+
+// canHold determines whether the node is a container for the segment
+func (jz *Jzon) canHold(seg segment) bool {
+	if seg.kind == _SegIndex {
+		return jz.Type == JzTypeArr
+	}
+
+	return jz.Type == JzTypeObj
+}
+
+func (jz *Jzon) setChild(seg segment, v *Jzon, create bool) (err error) {
+	if seg.kind == _SegKey {
+		return jz.Insert(seg.key, v)
+	}
+
+	if jz.Type != JzTypeArr {
+		return expectTypeOf(JzTypeArr, jz.Type)
+	}
+
+	arr := jz.data.([]*Jzon)
+	switch {
+	case seg.index < 0:
+		return errors.New("index is out of bound")
+	case seg.index < len(arr):
+		arr[seg.index] = v
+	case create && seg.index-len(arr) > SET_CREATE_MAX_GAP:
+		return fmt.Errorf("index %d is too far beyond the length %d", seg.index, len(arr))
+	case create:
+		for len(arr) < seg.index {
+			arr = append(arr, New(JzTypeNul))
+		}
+		jz.data = append(arr, v)
+	default:
+		return errors.New("index is out of bound")
+	}
+
+	return nil
+}
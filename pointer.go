@@ -0,0 +1,95 @@
+package jzon
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// pointerEscaper escapes a reference token of JSON Pointer (RFC 6901)
+var pointerEscaper = strings.NewReplacer("~", "~0", "/", "~1")
+
+// pointerUnescaper reverses pointerEscaper, `~01` is unescaped to `~1`
+var pointerUnescaper = strings.NewReplacer("~1", "/", "~0", "~")
+
+// formatPointer joins reference tokens to a JSON Pointer, such as `/a/0`,
+// the empty pointer refers to the whole document
+func formatPointer(tokens []string) string {
+	var sb strings.Builder
+	for _, tok := range tokens {
+		sb.WriteByte('/')
+		sb.WriteString(pointerEscaper.Replace(tok))
+	}
+
+	return sb.String()
+}
+
+// parsePointer splits a JSON Pointer to unescaped reference tokens
+func parsePointer(ptr string) (tokens []string, err error) {
+	if ptr == "" {
+		return nil, nil
+	}
+
+	if ptr[0] != '/' {
+		return nil, errors.New("expect a JSON Pointer beginning with '/'")
+	}
+
+	for _, tok := range strings.Split(ptr[1:], "/") {
+		for i := 0; i < len(tok); i++ {
+			if tok[i] == '~' && (i+1 == len(tok) || tok[i+1] != '0' && tok[i+1] != '1') {
+				return nil, errors.New("expect '~0' or '~1' in a JSON Pointer")
+			}
+		}
+
+		tokens = append(tokens, pointerUnescaper.Replace(tok))
+	}
+
+	return tokens, nil
+}
+
+// pointerIndex converts a reference token to an index of an array with n
+// elements, indices with leading zeros are invalid. the token `-` refers to
+// the index past the last element, which is only valid if allowEnd is set
+func pointerIndex(tok string, n int, allowEnd bool) (i int, err error) {
+	if tok == "-" && allowEnd {
+		return n, nil
+	}
+
+	if tok == "" || len(tok) > 1 && tok[0] == '0' || strings.TrimLeft(tok, "0123456789") != "" {
+		return 0, fmt.Errorf("expect an array index, but found `%s`", tok)
+	}
+
+	i, err = strconv.Atoi(tok)
+	if err != nil || i > n || i == n && !allowEnd {
+		return 0, fmt.Errorf("index %s is out of bound", tok)
+	}
+
+	return i, nil
+}
+
+// resolvePointer finds the node which tokens refer to
+func (jz *Jzon) resolvePointer(tokens []string) (curr *Jzon, err error) {
+	curr = jz
+	for _, tok := range tokens {
+		switch curr.Type {
+		case JzTypeObj:
+			if curr, err = curr.ValueOf(tok); err != nil {
+				return nil, err
+			}
+
+		case JzTypeArr:
+			arr := curr.data.([]*Jzon)
+			i, err := pointerIndex(tok, len(arr), false)
+			if err != nil {
+				return nil, err
+			}
+			curr = arr[i]
+
+		default:
+			return nil, fmt.Errorf("expect an object or an array at `%s`, but found %s", tok, typeStrings[curr.Type])
+		}
+	}
+
+	return curr, nil
+}
@@ -1,8 +1,12 @@
 package jzon
 
 import (
+	"errors"
 	"fmt"
+	"strconv"
 	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
 )
 
 // state indicates the inner state of the `parsePath` state machine
@@ -10,29 +14,18 @@ type state int64
 
 const (
 	// $.key1[1].big-array[1:4]
-	_Start     state = 2 << iota
-	_Dollar          // $        root
-	_Dot             // .        key mark
-	_LeftSB          // [        index mark
-	_RightSB         // ]        index end
-	_Key             // .*       object key
-	_Index           // [1-9]\d+ array index
-	_Colon           // :        slice mark
-	_Semicolon       // ;        line tail
+	_Start    state = 2 << iota
+	_Dollar         // $        root
+	_Dot            // .        key mark
+	_LeftSB         // [        index mark
+	_RightSB        // ]        index end
+	_Key            // .*       object key
+	_Index          // [1-9]\d+ array index
+	_Colon          // :        slice mark
+	_Wildcard       // *        any child
+	_Quoted         // '.*'     quoted key
 )
 
-var stateStrings = map[state]string{
-	_Start:     "_Start",
-	_Dollar:    "_Dollar",
-	_Dot:       "_Dot",
-	_LeftSB:    "_LeftSB",
-	_RightSB:   "_RightSB",
-	_Key:       "_Key",
-	_Index:     "_Index",
-	_Colon:     "_Colon",
-	_Semicolon: "_Semicolon",
-}
-
 func (st state) match(states ...state) bool {
 	for _, s := range states {
 		if uint64(st)&uint64(s) > 0 {
@@ -45,16 +38,12 @@ func (st state) match(states ...state) bool {
 // Query searches a child node in an object or an array, if the
 // node at the path doesn't exist, an error will be thrown out
 func (jz *Jzon) Query(path string) (g *Jzon, err error) {
-	// in the implements of function `parsePath()` we don't handle
-	// exceptions about slice bounds out of range. here we simply
-	// throw the error recovered from those unhandled exceptions
-	defer func() {
-		e := recover()
-		if e != nil {
-			err = fmt.Errorf("maybe out of bound: %v", e)
-		}
-	}()
-	return parsePath(jz, append([]byte(path), ';'))
+	p, err := CompilePath(path)
+	if err != nil {
+		return
+	}
+
+	return p.Get(jz)
 }
 
 // Search determines whether there exists the node on the given path
@@ -63,152 +52,301 @@ func (jz *Jzon) Search(path string) (exists bool) {
 	return found != nil
 }
 
-func expectState(real state, ex []state) error {
-	var sa []string
-	for _, s := range ex {
-		sa = append(sa, stateStrings[s])
+// QueryWithPaths searches all nodes matching the path, and reports where each
+// match comes from, if the path is malformed, an error will be thrown out
+func (jz *Jzon) QueryWithPaths(path string) (ms []Match, err error) {
+	p, err := CompilePath(path)
+	if err != nil {
+		return
+	}
+
+	return p.Matches(jz), nil
+}
+
+// Set replaces the node at the path with v, or inserts v if the last segment is a key
+// which doesn't exist. the parent of the node must exist, otherwise an error will be
+// thrown out. setting the root path `$` replaces the node itself
+func (jz *Jzon) Set(path string, v *Jzon) (err error) {
+	p, err := CompilePath(path)
+	if err != nil {
+		return
+	}
+
+	return p.Set(jz, v)
+}
+
+// SET_CREATE_MAX_GAP limits how many nulls `SetCreate()` pads an array with,
+// so a path such as `$.a[50000000]` can't make a huge array
+const SET_CREATE_MAX_GAP = 1024
+
+// SetCreate performs as `Set()`, except that it builds those missing intermediate
+// nodes, an object for a key segment and an array for an index segment. arrays
+// which are too short will be padded with null before the node is appended, if
+// more than SET_CREATE_MAX_GAP nulls are needed, an error will be thrown out
+func (jz *Jzon) SetCreate(path string, v *Jzon) (err error) {
+	p, err := CompilePath(path)
+	if err != nil {
+		return
 	}
-	expectStates := strings.Join(sa, " | ")
 
-	return fmt.Errorf("expect state %s, but the real state is %s", expectStates, stateStrings[real])
+	return p.SetCreate(jz, v)
+}
+
+// Unset removes the node at the path from its parent, if the node doesn't
+// exist or the path is the root path `$`, an error will be thrown out
+func (jz *Jzon) Unset(path string) (err error) {
+	p, err := CompilePath(path)
+	if err != nil {
+		return
+	}
+
+	return p.Unset(jz)
+}
+
+// PathError describes why a path is malformed, or why a lookup along
+// a well-formed path fails. for a malformed path, Offset is the offset
+// of the offending character in Path, counted in characters rather than
+// bytes, otherwise Offset is -1
+type PathError struct {
+	Path    string // the path being compiled or evaluated
+	Offset  int    // offset of the offending character, -1 for lookup failures
+	Segment string // the offending segment
+	Depth   int    // number of segments resolved before the failure
+	Msg     string // the readable reason
+}
+
+func (e *PathError) Error() string {
+	if e.Offset >= 0 {
+		return fmt.Sprintf("invalid path `%s` at offset %d near `%s`: %s", e.Path, e.Offset, e.Segment, e.Msg)
+	}
+
+	return fmt.Sprintf("path `%s` failed at segment `%s` after resolving %d segments: %s",
+		e.Path, e.Segment, e.Depth, e.Msg)
+}
+
+// stateExpectations describes what is expected after each state
+var stateExpectations = map[state]string{
+	_Start:    "expected '$' at the beginning of path",
+	_Dollar:   "expected '.', '[' or end of path after '$'",
+	_Dot:      "expected key name after '.'",
+	_Key:      "expected '.', '[' or end of path after key name",
+	_LeftSB:   "expected an index, '*' or a quoted key after '['",
+	_Index:    "expected ']' after index",
+	_Wildcard: "expected ']' after '*'",
+	_Quoted:   "expected ']' after quoted key",
+	_RightSB:  "expected '.', '[' or end of path after ']'",
 }
 
-func parsePath(root *Jzon, path []byte) (curr *Jzon, err error) {
+func parsePath(path []byte) (segs []segment, err error) {
 	var st = _Start
-	var ex = []state{_Dollar}
+	var rem = path
+	var segStart = 0
 	var key string
 
+	// fail reports an error at the first character of rem
+	fail := func(msg string) error {
+		offset := len(path) - len(rem)
+		_, size := utf8.DecodeRune(rem)
+		return &PathError{
+			Path:    string(path),
+			Offset:  utf8.RuneCount(path[:offset]),
+			Segment: string(path[segStart : offset+size]),
+			Depth:   len(segs),
+			Msg:     msg,
+		}
+	}
+
 	// a typical state machine model
 	for {
 		switch {
-		case path[0] == '$' && st.match(_Start):
-			ex = []state{_Dot, _LeftSB, _Semicolon}
-			st = _Dollar
-
-			curr = root
-			path = path[1:]
+		case len(rem) == 0 && st.match(_Dollar, _RightSB, _Key):
+			return segs, nil
 
-		case path[0] == ';' && st.match(_Dollar, _RightSB, _Key):
-			ex = []state{}
-			st = _Semicolon
+		case len(rem) == 0:
+			return nil, fail(stateExpectations[st])
 
-			return
+		case rem[0] == '$' && st.match(_Start):
+			st = _Dollar
+			rem = rem[1:]
 
-		case path[0] == '.' && st.match(_Dollar, _Key, _RightSB):
-			ex = []state{_Key}
+		case rem[0] == '.' && st.match(_Dollar, _Key, _RightSB):
 			st = _Dot
+			segStart = len(path) - len(rem)
+			rem = rem[1:]
 
-			path = path[1:]
-
-		case path[0] == '[' && st.match(_Dollar, _Key):
-			ex = []state{_Index}
+		case rem[0] == '[' && st.match(_Dollar, _Key, _RightSB):
 			st = _LeftSB
+			segStart = len(path) - len(rem)
+			rem = rem[1:]
 
-			path = path[1:]
-
-		case isDigit(path[0]) && st.match(_LeftSB):
-			ex = []state{_RightSB}
+		case isDigit(rem[0]) && st.match(_LeftSB):
 			st = _Index
 
-			var n int64
-			var f float64
-			var isInt bool
-			n, f, isInt, path, err = parseNumeric(path)
-			if err != nil {
-				return
+			var n = 0
+			for n < len(rem) && isDigit(rem[n]) {
+				n++
 			}
 
-			if !isInt {
-				err = fmt.Errorf("expect an integer index, but found float: %v", f)
-				return
+			index, e := strconv.Atoi(string(rem[:n]))
+			if e != nil {
+				return nil, fail("expected an integer index")
 			}
 
-			curr, err = curr.ValueAt(int(n))
+			rem = rem[n:]
+			segs = append(segs, segment{kind: _SegIndex, index: index})
+
+		case rem[0] == '*' && st.match(_LeftSB):
+			st = _Wildcard
+
+			segs = append(segs, segment{kind: _SegWildcard})
+			rem = rem[1:]
+
+		case (rem[0] == '\'' || rem[0] == '"') && st.match(_LeftSB):
+			st = _Quoted
+
+			var next []byte
+			key, next, err = parseQuotedPathKey(rem)
+			rem = next
 			if err != nil {
-				return
+				return nil, fail(err.Error())
 			}
 
-		case path[0] == ']' && st.match(_Index):
-			ex = []state{_Dot, _Semicolon}
-			st = _RightSB
+			segs = append(segs, segment{kind: _SegKey, key: key})
 
-			path = path[1:]
+		case rem[0] == ']' && st.match(_Index, _Wildcard, _Quoted):
+			st = _RightSB
+			rem = rem[1:]
 
-		case st.match(_Dot):
-			ex = []state{_Dot, _LeftSB, _Semicolon}
+		case rem[0] != '.' && rem[0] != '[' && st.match(_Dot):
 			st = _Key
 
-			key, path, err = parsePathKey(path)
+			var next []byte
+			key, next, err = parsePathKey(rem)
+			rem = next
 			if err != nil {
-				return
-			}
-			curr, err = curr.ValueOf(key)
-			if err != nil {
-				return
+				return nil, fail(err.Error())
 			}
 
+			segs = append(segs, segment{kind: _SegKey, key: key})
+
 		default:
-			return nil, expectState(st, ex)
+			return nil, fail(stateExpectations[st])
 		}
 	}
 }
 
-// parsePathKey parses as `parseKey()`, except that the given string
-// isn't surrounded with ", and it will escape some more characters
+// parsePathKey parses as `parseKey()`, except that the given string isn't
+// surrounded with ", and it will escape some more characters. on failure
+// the returned rem begins with the offending character
 func parsePathKey(path []byte) (k string, rem []byte, err error) {
 	var parsed = make([]byte, 0, SHORT_STRING_OPTIMIZED_CAP)
-	var c byte
 
 	rem = path
 
-	for {
+	for len(rem) > 0 {
 		switch {
-		case rem[0] == '\\' && rem[1] == 'u':
-			utf8str := make([]byte, 0, SHORT_STRING_OPTIMIZED_CAP)
-			utf8str, rem, err = parseUnicode(rem)
-			for _, c := range utf8str {
-				parsed = append(parsed, c)
+		case rem[0] == '\\' && len(rem) > 1 && strings.IndexByte(".[];", rem[1]) >= 0:
+			parsed = append(parsed, rem[1])
+			rem = rem[2:]
+
+		case rem[0] == '\\':
+			if parsed, rem, err = parsePathEscape(parsed, rem); err != nil {
+				return
 			}
-			continue
 
-		case rem[0] == '\\' && rem[1] == '.':
-			parsed = append(parsed, '.')
-			rem = rem[2:]
-			continue
+		case rem[0] == '.' || rem[0] == '[' || rem[0] == ';':
+			return string(parsed), rem, nil
 
-		case rem[0] == '\\' && rem[1] == '[':
-			parsed = append(parsed, '[')
-			rem = rem[2:]
-			continue
+		default:
+			_, size := utf8.DecodeRune(rem)
+			parsed = append(parsed, rem[:size]...)
+			rem = rem[size:]
+		}
+	}
 
-		case rem[0] == '\\' && rem[1] == ']':
-			parsed = append(parsed, ']')
-			rem = rem[2:]
-			continue
+	return string(parsed), rem, nil
+}
+
+// parseQuotedPathKey parses a key surrounded with ' or " in brackets, like
+// `['a.b']` or `["a]b"]`, escapes are the same as JSON strings, plus \'
+func parseQuotedPathKey(path []byte) (k string, rem []byte, err error) {
+	var parsed = make([]byte, 0, SHORT_STRING_OPTIMIZED_CAP)
+	var quote = path[0]
+
+	rem = path[1:]
+
+	for len(rem) > 0 {
+		switch {
+		case rem[0] == quote:
+			return string(parsed), rem[1:], nil
 
-		case rem[0] == '\\' && rem[1] == ';':
-			parsed = append(parsed, ';')
+		case rem[0] == '\\' && len(rem) > 1 && rem[1] == '\'':
+			parsed = append(parsed, '\'')
 			rem = rem[2:]
-			continue
 
-		case rem[0] == '\\' && rem[1] != 'u':
-			c, rem, err = parseEscaped(rem)
-			if err != nil {
+		case rem[0] == '\\':
+			if parsed, rem, err = parsePathEscape(parsed, rem); err != nil {
 				return
 			}
-			parsed = append(parsed, c)
-			continue
-
-		case rem[0] == '.' || rem[0] == '[' || rem[0] == ';':
-			goto End
 
 		default:
-			parsed = append(parsed, rem[0])
-			pos.col += 1
-			rem = rem[1:]
-			continue
+			_, size := utf8.DecodeRune(rem)
+			parsed = append(parsed, rem[:size]...)
+			rem = rem[size:]
 		}
 	}
-End:
-	return string(parsed), rem, nil
+
+	return "", rem, fmt.Errorf("expected closing %c of quoted key", quote)
+}
+
+// parsePathEscape appends the character escaped at the beginning of rem to
+// parsed, JSON escapes are supported, including \uXXXX and surrogate pairs.
+// unlike `parseUnicode()` it never touches the global `pos`
+func parsePathEscape(parsed []byte, path []byte) (appended []byte, rem []byte, err error) {
+	rem = path
+
+	switch {
+	case len(rem) == 1:
+		return parsed, rem, errors.New("expected an escaped character after '\\'")
+
+	case rem[1] == 'u':
+		r, ok := parsePathHex4(rem)
+		if !ok {
+			return parsed, rem, errors.New("expected a valid \\uXXXX escape")
+		}
+		rem = rem[6:]
+
+		if utf16.IsSurrogate(r) {
+			r2, ok := parsePathHex4(rem)
+			if r = utf16.DecodeRune(r, r2); !ok || r == utf8.RuneError {
+				return parsed, path, errors.New("expected a valid \\uXXXX surrogate pair")
+			}
+			rem = rem[6:]
+		}
+
+		var buf [utf8.UTFMax]byte
+		n := utf8.EncodeRune(buf[:], r)
+		return append(parsed, buf[:n]...), rem, nil
+	}
+
+	c, ok := escapeMap[rem[1]]
+	if !ok {
+		return parsed, rem, fmt.Errorf("unknown escape '\\%c'", rem[1])
+	}
+
+	return append(parsed, c), rem[2:], nil
+}
+
+// parsePathHex4 parses the rune of `\uXXXX` at the beginning of path
+func parsePathHex4(path []byte) (r rune, ok bool) {
+	if len(path) < 6 || path[0] != '\\' || path[1] != 'u' {
+		return 0, false
+	}
+
+	n, err := strconv.ParseUint(string(path[2:6]), 16, 32)
+	if err != nil {
+		return 0, false
+	}
+
+	return rune(n), true
 }
@@ -0,0 +1,161 @@
+package jzon
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// RedactAction tells what to do with a node matched by a RedactRule
+type RedactAction int
+
+const (
+	// RedactMask replaces the node with the string REDACT_MASK
+	RedactMask RedactAction = iota
+	// RedactRemove removes the node from its parent
+	RedactRemove
+	// RedactKeepLast keeps the last KeepLast characters of a string and masks
+	// the others, a value which is not a string is formatted first
+	RedactKeepLast
+	// RedactHash replaces the node with the hex SHA-256 digest of the salt
+	// followed by the canonical form of the node, so equal values still match
+	RedactHash
+)
+
+// REDACT_MASK is the replacement of masked values
+const REDACT_MASK = "***"
+
+// RedactRule matches nodes by their keys, paths or values, a node matches if any
+// of the matchers set in the rule matches, and the Action applies to the node
+type RedactRule struct {
+	// Keys are names of keys compared case-insensitively
+	Keys []string
+
+	// KeyPattern matches keys by a regular expression
+	KeyPattern *regexp.Regexp
+
+	// Paths match positions of nodes, wildcards in them match any key or index
+	Paths []*Path
+
+	// Predicate matches nodes by the path and the value
+	Predicate func(path Path, node *Jzon) bool
+
+	Action RedactAction
+
+	// KeepLast is the number of characters kept by RedactKeepLast
+	KeepLast int
+
+	// Salt is prepended to the value hashed by RedactHash
+	Salt []byte
+}
+
+// DefaultRedactRules mask values of keys which commonly hold passwords, tokens,
+// keys, credentials and card numbers, it's used when no rule is given to `Redact()`
+var DefaultRedactRules = []RedactRule{
+	{
+		KeyPattern: regexp.MustCompile(`(?i)(passw(or)?d|^pwd$|secret|token|api[-_]?key|^auth$|authorization|cookie|session[-_]?id|` +
+			`private[-_]?key|credential|credit[-_]?card|card[-_]?number|cvv|^ssn$)`),
+		Action: RedactMask,
+	},
+}
+
+// match reports whether the rule matches the node at the path
+func (rule *RedactRule) match(path Path, node *Jzon) bool {
+	if n := len(path.segs); n > 0 && path.segs[n-1].kind == _SegKey {
+		k := path.segs[n-1].key
+		for _, key := range rule.Keys {
+			if strings.EqualFold(k, key) {
+				return true
+			}
+		}
+		if rule.KeyPattern != nil && rule.KeyPattern.MatchString(k) {
+			return true
+		}
+	}
+
+	for _, p := range rule.Paths {
+		if p.matchSegments(path.segs) {
+			return true
+		}
+	}
+
+	return rule.Predicate != nil && rule.Predicate(path, node)
+}
+
+// matchSegments reports whether the path selects the node at segs
+func (p *Path) matchSegments(segs []segment) bool {
+	if len(p.segs) != len(segs) {
+		return false
+	}
+
+	for i, seg := range p.segs {
+		if seg.kind != _SegWildcard && seg != segs[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// apply returns the replacement of the node
+func (rule *RedactRule) apply(node *Jzon) *Jzon {
+	switch rule.Action {
+	case RedactKeepLast:
+		var s string
+		switch node.Type {
+		case JzTypeStr:
+			s = node.data.(string)
+		case JzTypeInt:
+			s = strconv.FormatInt(node.data.(int64), 10)
+		case JzTypeFlt:
+			s = strconv.FormatFloat(node.data.(float64), 'g', -1, 64)
+		default:
+			return NewFromAny(REDACT_MASK)
+		}
+		if n := utf8.RuneCountInString(s); rule.KeepLast < n {
+			r := []rune(s)
+			s = REDACT_MASK + string(r[n-max(rule.KeepLast, 0):])
+		}
+		return NewFromAny(s)
+
+	case RedactHash:
+		h := sha256.New()
+		h.Write(rule.Salt)
+		node.writeCanonical(h)
+		return NewFromAny(hex.EncodeToString(h.Sum(nil)))
+	}
+
+	return NewFromAny(REDACT_MASK)
+}
+
+// Redact returns a deep clone of the node in which nodes matched by the rules are
+// removed or masked, the node itself is left intact. rules are tried in order and
+// the first matching rule applies, children of a redacted node are not visited.
+// if no rule is given, DefaultRedactRules are used
+//
+//	clean := jz.Redact(jzon.RedactRule{Keys: []string{"email"}, Action: jzon.RedactKeepLast, KeepLast: 4})
+func (jz *Jzon) Redact(rules ...RedactRule) (res *Jzon) {
+	if len(rules) == 0 {
+		rules = DefaultRedactRules
+	}
+
+	res = jz.DeepClone()
+	res.WalkMut(func(path Path, node *Jzon) (*Jzon, WalkAction) {
+		for i := range rules {
+			if !rules[i].match(path, node) {
+				continue
+			}
+			if rules[i].Action == RedactRemove {
+				return nil, WalkDelete
+			}
+			return rules[i].apply(node), WalkSkipChildren
+		}
+
+		return nil, WalkContinue
+	})
+
+	return res
+}
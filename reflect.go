@@ -2,6 +2,7 @@ package jzon
 
 import (
 	"fmt"
+	"math"
 	"reflect"
 )
 
@@ -16,6 +17,11 @@ type Deserializable interface {
 	Deserialize([]byte, *Any)
 }
 
+var (
+	jzonType    = reflect.TypeOf(Jzon{})
+	jzonPtrType = reflect.TypeOf(&Jzon{})
+)
+
 // TAG_NAME is the default leading tag for tagging a structure field
 var TAG_NAME = "json"
 
@@ -32,10 +38,48 @@ func Serialize(s Any) (jz *Jzon, err error) {
 	return serialize(v)
 }
 
+// visit identifies a pointer, map or slice being serialized, the type tells
+// a structure from its first field, and the length tells slices of the same array
+type visit struct {
+	t   reflect.Type
+	ptr uintptr
+	len int
+}
+
 func serialize(v reflect.Value) (jz *Jzon, err error) {
+	return serializeValue(v, make(map[visit]bool))
+}
+
+// serializeValue serializes the value, visiting holds the pointers, maps and
+// slices on the way from the root, so a value which contains itself is an error
+func serializeValue(v reflect.Value, visiting map[visit]bool) (jz *Jzon, err error) {
+	if !v.IsValid() {
+		return New(JzTypeNul), nil
+	}
+
 	t := v.Type()
 	k := v.Kind()
 
+	if t == jzonType || t == jzonPtrType {
+		// nodes in unexported fields can't be read out, they are null as nil nodes
+		if t == jzonPtrType && v.IsNil() || !v.CanInterface() {
+			return New(JzTypeNul), nil
+		}
+		return NewFromAny(v.Interface()), nil
+	}
+
+	if (k == reflect.Ptr || k == reflect.Map || k == reflect.Slice) && !v.IsNil() {
+		key := visit{t: t, ptr: v.Pointer()}
+		if k == reflect.Slice {
+			key.len = v.Len()
+		}
+		if visiting[key] {
+			return nil, fmt.Errorf("can not serialize cyclic value of type %s", t)
+		}
+		visiting[key] = true
+		defer delete(visiting, key)
+	}
+
 	// TODO: serialize those types which implemented interface `jzon.Serializable`
 	// method := v.MethodByName("Serialize")
 	// if method.IsValid() {
@@ -49,7 +93,7 @@ func serialize(v reflect.Value) (jz *Jzon, err error) {
 		var val *Jzon
 		for i := 0; i < t.NumField(); i++ {
 			key := t.Field(i).Tag.Get(TAG_NAME)
-			val, err = serialize(v.Field(i))
+			val, err = serializeValue(v.Field(i), visiting)
 			if err != nil {
 				return
 			}
@@ -96,7 +140,7 @@ func serialize(v reflect.Value) (jz *Jzon, err error) {
 		var keys = v.MapKeys()
 		var str string
 		for _, key := range keys {
-			val, err = serialize(v.MapIndex(key))
+			val, err = serializeValue(v.MapIndex(key), visiting)
 			if err != nil {
 				return
 			}
@@ -117,7 +161,7 @@ func serialize(v reflect.Value) (jz *Jzon, err error) {
 		jz = New(JzTypeArr)
 		var val *Jzon
 		for i := 0; i < v.Len(); i++ {
-			val, err = serialize(v.Index(i))
+			val, err = serializeValue(v.Index(i), visiting)
 			if err != nil {
 				return
 			}
@@ -133,17 +177,23 @@ func serialize(v reflect.Value) (jz *Jzon, err error) {
 	case reflect.Bool:
 		jz = NewFromAny(v.Bool())
 
-	case reflect.Ptr:
+	case reflect.Ptr, reflect.Interface:
 		if v.IsNil() {
 			jz = New(JzTypeNul)
 		} else {
-			err = fmt.Errorf("only nil ptr can be serialized")
+			jz, err = serializeValue(v.Elem(), visiting)
 		}
 
-	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
-		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
 		jz = NewFromAny(v.Int())
 
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if v.Uint() > math.MaxInt64 {
+			err = fmt.Errorf("%d overflows the integer of Jzon", v.Uint())
+		} else {
+			jz = NewFromAny(int64(v.Uint()))
+		}
+
 	default:
 		err = fmt.Errorf("can not serialize variable of kind [%s] to Jzon", k)
 	}
@@ -283,7 +333,7 @@ func (jz *Jzon) Value(t ValueType) (v Any, err error) {
 }
 
 // NewFromAny allocates and assigns a Jzon node on the heap, if the given `v` is of type
-// `*Jzon`, it performs as deep clone, if `v` is of type `Jzon`, it performs as shallow
+// `*Jzon`, it performs as shallow clone, if `v` is of type `Jzon`, it performs as deep
 // clone, otherwise it converts value of built-in types to an appropriate `Jzon` value
 func NewFromAny(v Any) *Jzon {
 	jz := new(Jzon)
@@ -329,7 +379,7 @@ func NewFromAny(v Any) *Jzon {
 
 	case []byte:
 		jz.Type = JzTypeStr
-		jz.data = v
+		jz.data = string(realv)
 
 	case bool:
 		jz.Type = JzTypeBol
@@ -344,12 +394,14 @@ func NewFromAny(v Any) *Jzon {
 		jz.data = v
 
 	case *Jzon:
-		// TODO: shallow clone
-		jz = v.(*Jzon)
+		if realv == nil {
+			jz.Type = JzTypeNul
+			return jz
+		}
+		jz = realv.Clone()
 
 	case Jzon:
-		// TODO: deep clone
-		return nil
+		jz = realv.DeepClone()
 
 	default:
 		var err error
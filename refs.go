@@ -0,0 +1,360 @@
+package jzon
+
+import (
+	"fmt"
+	"io/fs"
+	"net/url"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// REF_KEY is the member of a JSON Reference object, such as `{"$ref": "#/a"}`
+const REF_KEY = "$ref"
+
+// BUNDLE_DEFINITIONS is the member of the root object where `Bundle()` gathers
+// the external documents
+const BUNDLE_DEFINITIONS = "definitions"
+
+// Loader loads the document of a URI referenced by `$ref`, URIs of relative
+// references are resolved against the referencing document before loading
+type Loader interface {
+	Load(uri string) (*Jzon, error)
+}
+
+// LoaderFunc adapts a function to a Loader
+type LoaderFunc func(uri string) (*Jzon, error)
+
+// Load calls fn(uri)
+func (fn LoaderFunc) Load(uri string) (*Jzon, error) {
+	return fn(uri)
+}
+
+// FSLoader loads documents from a file system, such as `os.DirFS(dir)`, or
+// `fstest.MapFS` in tests, URIs are paths in the file system
+type FSLoader struct {
+	FS fs.FS
+}
+
+// Load reads and parses the file at the uri
+func (l FSLoader) Load(uri string) (jz *Jzon, err error) {
+	content, err := fs.ReadFile(l.FS, uri)
+	if err != nil {
+		return
+	}
+
+	return Parse(content)
+}
+
+// RefOptions controls how `ResolveRefsWith()` and `BundleWith()` resolve references
+type RefOptions struct {
+	// Base is the URI of the root document, references to other documents are
+	// resolved against it, and references to it are local references
+	Base string
+
+	// KeepCycles keeps cyclic references as links to the shared node which is
+	// being resolved, so the result is a graph instead of a tree, which can't be
+	// serialized or walked without care. otherwise cycles are errors
+	KeepCycles bool
+}
+
+// ResolveRefs returns a copy of the node in which each JSON Reference object,
+// `{"$ref": "#/definitions/x"}` or `{"$ref": "other.json#/a"}`, is replaced by
+// the node it refers to, recursively. other documents are loaded by the loader,
+// and each of them is loaded once. if a reference can't be resolved or refers
+// to itself through other references, an error will be thrown out
+func ResolveRefs(jz *Jzon, loader Loader) (res *Jzon, err error) {
+	return ResolveRefsWith(jz, loader, RefOptions{})
+}
+
+// ResolveRefsWith resolves references as `ResolveRefs()` does, with options
+func ResolveRefsWith(jz *Jzon, loader Loader, opts RefOptions) (res *Jzon, err error) {
+	r := &refResolver{
+		loader:  loader,
+		opts:    opts,
+		docs:    map[string]*Jzon{opts.Base: jz},
+		pending: make(map[string]*Jzon),
+		filling: make(map[*Jzon]bool),
+	}
+
+	return r.resolve(jz, opts.Base)
+}
+
+type refResolver struct {
+	loader Loader
+	opts   RefOptions
+	docs   map[string]*Jzon
+
+	// pending maps references being resolved to the nodes which will hold the
+	// results, and filling marks those nodes until they are filled
+	pending map[string]*Jzon
+	filling map[*Jzon]bool
+}
+
+func (r *refResolver) resolve(node *Jzon, base string) (res *Jzon, err error) {
+	if ref, ok := refOf(node); ok {
+		return r.resolveRef(ref, base)
+	}
+
+	switch node.Type {
+	case JzTypeObj:
+		res = New(JzTypeObj)
+		m := res.data.(map[string]*Jzon)
+		for k, v := range node.data.(map[string]*Jzon) {
+			if m[k], err = r.resolve(v, base); err != nil {
+				return nil, err
+			}
+		}
+		return res, nil
+
+	case JzTypeArr:
+		arr := node.data.([]*Jzon)
+		elems := make([]*Jzon, len(arr))
+		for i, v := range arr {
+			if elems[i], err = r.resolve(v, base); err != nil {
+				return nil, err
+			}
+		}
+		return NewFromAny(elems), nil
+	}
+
+	return node.Clone(), nil
+}
+
+func (r *refResolver) resolveRef(ref string, base string) (res *Jzon, err error) {
+	uri, tokens, err := parseRef(ref, base)
+	if err != nil {
+		return
+	}
+
+	key := uri + "#" + formatPointer(tokens)
+	if holder, ok := r.pending[key]; ok {
+		if !r.opts.KeepCycles {
+			return nil, fmt.Errorf("cyclic reference `%s`", ref)
+		}
+		return holder, nil
+	}
+
+	target, err := r.load(uri, tokens)
+	if err != nil {
+		return nil, fmt.Errorf("can not resolve reference `%s`: %v", ref, err)
+	}
+
+	holder := new(Jzon)
+	r.pending[key], r.filling[holder] = holder, true
+	defer delete(r.pending, key)
+
+	if res, err = r.resolve(target, uri); err != nil {
+		return
+	}
+
+	// a reference which only refers to references back to itself has no content
+	if r.filling[res] {
+		return nil, fmt.Errorf("cyclic reference `%s` without content", ref)
+	}
+
+	*holder = *res
+	delete(r.filling, holder)
+
+	return holder, nil
+}
+
+// load returns the node at the pointer in the document of the uri
+func (r *refResolver) load(uri string, tokens []string) (target *Jzon, err error) {
+	doc, ok := r.docs[uri]
+	if !ok {
+		if r.loader == nil {
+			return nil, fmt.Errorf("no loader for document `%s`", uri)
+		}
+		if doc, err = r.loader.Load(uri); err != nil {
+			return
+		}
+		r.docs[uri] = doc
+	}
+
+	return doc.resolvePointer(tokens)
+}
+
+// refOf returns the reference if the node is a JSON Reference object
+func refOf(node *Jzon) (ref string, ok bool) {
+	if node.Type != JzTypeObj {
+		return "", false
+	}
+
+	v, exists := node.data.(map[string]*Jzon)[REF_KEY]
+	if !exists || v.Type != JzTypeStr {
+		return "", false
+	}
+
+	return v.data.(string), true
+}
+
+// parseRef splits a reference to the URI of the document, resolved against
+// base, and the tokens of the JSON Pointer in the fragment
+func parseRef(ref string, base string) (uri string, tokens []string, err error) {
+	uri, fragment, _ := strings.Cut(ref, "#")
+	if fragment, err = url.PathUnescape(fragment); err != nil {
+		return
+	}
+
+	if tokens, err = parsePointer(fragment); err != nil {
+		return "", nil, fmt.Errorf("malformed reference `%s`: %v", ref, err)
+	}
+
+	switch {
+	case uri == "":
+		uri = base
+	case !strings.Contains(uri, "://") && !path.IsAbs(uri):
+		uri = path.Join(path.Dir(base), uri)
+	}
+
+	return uri, tokens, nil
+}
+
+// Bundle returns a copy of the node in which the documents referenced by it are
+// gathered, each referenced node of another document is copied to a member of
+// the `definitions` object of the root, and the reference is rewritten to a
+// local one such as `{"$ref": "#/definitions/other_a"}`. local references are
+// kept, so the result still needs `ResolveRefs()` to be inlined, but no longer
+// needs the loader. if the node is not an object, or any reference can't be
+// resolved, an error will be thrown out
+func Bundle(jz *Jzon, loader Loader) (res *Jzon, err error) {
+	return BundleWith(jz, loader, RefOptions{})
+}
+
+// BundleWith bundles the documents as `Bundle()` does, with options,
+// KeepCycles is meaningless here since cycles are kept as references
+func BundleWith(jz *Jzon, loader Loader, opts RefOptions) (res *Jzon, err error) {
+	if jz.Type != JzTypeObj {
+		return nil, expectTypeOf(JzTypeObj, jz.Type)
+	}
+
+	b := &bundler{
+		refResolver: refResolver{loader: loader, opts: opts, docs: map[string]*Jzon{opts.Base: jz}},
+		defs:        New(JzTypeObj),
+		names:       make(map[string]string),
+		taken:       make(map[string]bool),
+	}
+
+	existing, err := jz.ValueOf(BUNDLE_DEFINITIONS)
+	if err == nil {
+		if existing.Type != JzTypeObj {
+			return nil, fmt.Errorf("expect `%s` to be an object, but found %s", BUNDLE_DEFINITIONS, typeStrings[existing.Type])
+		}
+		for k := range existing.data.(map[string]*Jzon) {
+			b.taken[k] = true
+		}
+	}
+
+	if res, err = b.rewrite(jz, opts.Base); err != nil {
+		return
+	}
+
+	if len(b.defs.data.(map[string]*Jzon)) > 0 {
+		defs, err := res.ValueOf(BUNDLE_DEFINITIONS)
+		if err != nil {
+			defs = New(JzTypeObj)
+			res.Insert(BUNDLE_DEFINITIONS, defs)
+		}
+		for k, v := range b.defs.data.(map[string]*Jzon) {
+			defs.Insert(k, v)
+		}
+	}
+
+	return res, nil
+}
+
+type bundler struct {
+	refResolver
+
+	// defs holds the gathered nodes, names maps references to the members of
+	// them, and taken holds the names in use
+	defs  *Jzon
+	names map[string]string
+	taken map[string]bool
+}
+
+var nonIdentChars = regexp.MustCompile(`[^A-Za-z0-9_]+`)
+
+func (b *bundler) rewrite(node *Jzon, base string) (res *Jzon, err error) {
+	if ref, ok := refOf(node); ok {
+		uri, tokens, err := parseRef(ref, base)
+		if err != nil {
+			return nil, err
+		}
+		if uri == b.opts.Base {
+			return localRef(tokens), nil
+		}
+		return b.gather(ref, uri, tokens)
+	}
+
+	switch node.Type {
+	case JzTypeObj:
+		res = New(JzTypeObj)
+		m := res.data.(map[string]*Jzon)
+		// keys are sorted, so names of the definitions are stable
+		for _, k := range node.sortedKeys() {
+			if m[k], err = b.rewrite(node.data.(map[string]*Jzon)[k], base); err != nil {
+				return nil, err
+			}
+		}
+		return res, nil
+
+	case JzTypeArr:
+		arr := node.data.([]*Jzon)
+		elems := make([]*Jzon, len(arr))
+		for i, v := range arr {
+			if elems[i], err = b.rewrite(v, base); err != nil {
+				return nil, err
+			}
+		}
+		return NewFromAny(elems), nil
+	}
+
+	return node.Clone(), nil
+}
+
+// gather copies the node referenced in another document to the definitions
+// once, and returns the local reference to the copy
+func (b *bundler) gather(ref string, uri string, tokens []string) (res *Jzon, err error) {
+	key := uri + "#" + formatPointer(tokens)
+	if name, ok := b.names[key]; ok {
+		return localRef([]string{BUNDLE_DEFINITIONS, name}), nil
+	}
+
+	target, err := b.load(uri, tokens)
+	if err != nil {
+		return nil, fmt.Errorf("can not resolve reference `%s`: %v", ref, err)
+	}
+
+	// "other.json#/a/b" is named as "other_a_b"
+	name := strings.TrimSuffix(path.Base(uri), path.Ext(uri))
+	if len(tokens) > 0 {
+		name += "_" + strings.Join(tokens, "_")
+	}
+	name = strings.Trim(nonIdentChars.ReplaceAllString(name, "_"), "_")
+	if name == "" {
+		name = "def"
+	}
+	for i, n := 2, name; b.taken[name]; i++ {
+		name = n + "_" + strconv.Itoa(i)
+	}
+	b.names[key], b.taken[name] = name, true
+
+	// names are reserved before rewriting, so cyclic references end up here
+	def, err := b.rewrite(target, uri)
+	if err != nil {
+		return
+	}
+	b.defs.Insert(name, def)
+
+	return localRef([]string{BUNDLE_DEFINITIONS, name}), nil
+}
+
+// localRef returns a reference object to the pointer in the root document
+func localRef(tokens []string) *Jzon {
+	res := New(JzTypeObj)
+	res.Insert(REF_KEY, NewFromAny("#"+formatPointer(tokens)))
+	return res
+}
@@ -0,0 +1,257 @@
+package jzon
+
+import (
+	"sort"
+	"unicode/utf8"
+)
+
+// SCHEMA_DRAFT is the `$schema` of documents produced by `InferSchema()`
+const SCHEMA_DRAFT = "http://json-schema.org/draft-07/schema#"
+
+// SchemaOptions controls how `InferSchemaWith()` generalizes the samples
+type SchemaOptions struct {
+	// MaxEnum is the maximum number of distinct strings or integers of a
+	// value which are listed as enum candidates, it's 8 by default, and a
+	// negative number disables enums
+	MaxEnum int
+
+	// MinEnumRepeat is the number of times each distinct value must be seen
+	// on average for the value to be an enum, it's 2 by default, so a value
+	// seen once in every sample is never taken as an enum
+	MinEnumRepeat int
+}
+
+func (opts SchemaOptions) maxEnum() int {
+	if opts.MaxEnum == 0 {
+		return 8
+	}
+	return opts.MaxEnum
+}
+
+func (opts SchemaOptions) minEnumRepeat() int {
+	if opts.MinEnumRepeat <= 0 {
+		return 2
+	}
+	return opts.MinEnumRepeat
+}
+
+// shape accumulates everything seen at one position of the samples
+type shape struct {
+	count int
+	types map[ValueType]int
+
+	// numbers, the least and the greatest ones are kept as they are, so integers
+	// beyond 2^53 don't lose precision
+	min, max *Jzon
+
+	// strings
+	minLen, maxLen int
+
+	// enum candidates keyed by the compact form, nil once there are too many
+	values map[string]*Jzon
+
+	// objects, a key is required if it's seen in all the objects
+	objects int
+	props   map[string]*shape
+
+	// arrays
+	minItems, maxItems int
+	items              *shape
+}
+
+func newShape() *shape {
+	return &shape{
+		types:  make(map[ValueType]int),
+		values: make(map[string]*Jzon),
+		props:  make(map[string]*shape),
+	}
+}
+
+func (s *shape) add(jz *Jzon, opts SchemaOptions) {
+	first := s.types[jz.Type] == 0
+	s.count++
+	s.types[jz.Type]++
+
+	switch jz.Type {
+	case JzTypeInt, JzTypeFlt:
+		if s.min == nil || Compare(jz, s.min) < 0 {
+			s.min = jz
+		}
+		if s.max == nil || Compare(jz, s.max) > 0 {
+			s.max = jz
+		}
+
+	case JzTypeStr:
+		n := utf8.RuneCountInString(jz.data.(string))
+		if first {
+			s.minLen, s.maxLen = n, n
+		}
+		s.minLen, s.maxLen = min(s.minLen, n), max(s.maxLen, n)
+
+	case JzTypeObj:
+		s.objects++
+		for k, v := range jz.data.(map[string]*Jzon) {
+			p, ok := s.props[k]
+			if !ok {
+				p = newShape()
+				s.props[k] = p
+			}
+			p.add(v, opts)
+		}
+
+	case JzTypeArr:
+		arr := jz.data.([]*Jzon)
+		if first {
+			s.minItems, s.maxItems = len(arr), len(arr)
+		}
+		s.minItems, s.maxItems = min(s.minItems, len(arr)), max(s.maxItems, len(arr))
+		for _, elem := range arr {
+			if s.items == nil {
+				s.items = newShape()
+			}
+			s.items.add(elem, opts)
+		}
+	}
+
+	if s.values != nil && (jz.Type == JzTypeStr || jz.Type == JzTypeInt) {
+		s.values[jz.Compact()] = jz
+		if len(s.values) > opts.maxEnum() {
+			s.values = nil
+		}
+	}
+}
+
+// typeNames are the JSON Schema names of the value types
+var typeNames = map[ValueType]string{
+	JzTypeStr: "string",
+	JzTypeInt: "integer",
+	JzTypeFlt: "number",
+	JzTypeBol: "boolean",
+	JzTypeObj: "object",
+	JzTypeArr: "array",
+	JzTypeNul: "null",
+}
+
+// schema converts the accumulated shape to a JSON Schema
+func (s *shape) schema(opts SchemaOptions) *Jzon {
+	var res = New(JzTypeObj)
+	if s == nil || s.count == 0 {
+		return res
+	}
+
+	// integers are numbers as well, so "number" covers both
+	var names []string
+	for _, t := range []ValueType{JzTypeNul, JzTypeBol, JzTypeInt, JzTypeFlt, JzTypeStr, JzTypeArr, JzTypeObj} {
+		if s.types[t] > 0 && !(t == JzTypeInt && s.types[JzTypeFlt] > 0) {
+			names = append(names, typeNames[t])
+		}
+	}
+
+	if len(names) == 1 {
+		res.Insert("type", NewFromAny(names[0]))
+	} else {
+		types := New(JzTypeArr)
+		for _, name := range names {
+			types.Append(NewFromAny(name))
+		}
+		res.Insert("type", types)
+	}
+
+	if enum := s.enum(opts); enum != nil {
+		res.Insert("enum", enum)
+		return res
+	}
+
+	if s.min != nil {
+		res.Insert("minimum", s.min.Clone())
+		res.Insert("maximum", s.max.Clone())
+	}
+
+	if s.types[JzTypeStr] > 0 {
+		res.Insert("minLength", NewFromAny(s.minLen))
+		res.Insert("maxLength", NewFromAny(s.maxLen))
+	}
+
+	if s.types[JzTypeArr] > 0 {
+		res.Insert("minItems", NewFromAny(s.minItems))
+		res.Insert("maxItems", NewFromAny(s.maxItems))
+		if s.items != nil {
+			res.Insert("items", s.items.schema(opts))
+		}
+	}
+
+	if s.types[JzTypeObj] > 0 {
+		var keys = make([]string, 0, len(s.props))
+		for k := range s.props {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		props, required := New(JzTypeObj), New(JzTypeArr)
+		for _, k := range keys {
+			props.Insert(k, s.props[k].schema(opts))
+			if s.props[k].count == s.objects {
+				required.Append(NewFromAny(k))
+			}
+		}
+		res.Insert("properties", props)
+		res.Insert("required", required)
+	}
+
+	return res
+}
+
+// enum returns the enum candidates sorted, or nil if the value doesn't look like
+// an enum: some value which is not a string, an integer or null was seen, there
+// are too many distinct values, or the values repeat too few times
+func (s *shape) enum(opts SchemaOptions) *Jzon {
+	if s.values == nil || len(s.values) == 0 || opts.maxEnum() < 0 {
+		return nil
+	}
+
+	if s.types[JzTypeStr]+s.types[JzTypeInt]+s.types[JzTypeNul] != s.count {
+		return nil
+	}
+
+	if s.types[JzTypeStr]+s.types[JzTypeInt] < len(s.values)*opts.minEnumRepeat() {
+		return nil
+	}
+
+	var values = make([]*Jzon, 0, len(s.values)+1)
+	for _, v := range s.values {
+		values = append(values, v.Clone())
+	}
+	sort.SliceStable(values, func(i, j int) bool {
+		return Compare(values[i], values[j]) < 0
+	})
+
+	if s.types[JzTypeNul] > 0 {
+		values = append([]*Jzon{New(JzTypeNul)}, values...)
+	}
+
+	return NewFromAny(values)
+}
+
+// InferSchema generalizes the samples to a JSON Schema (draft-07) which
+// accepts all of them, with the default options, see `InferSchemaWith()`
+func InferSchema(samples ...*Jzon) *Jzon {
+	return InferSchemaWith(SchemaOptions{}, samples...)
+}
+
+// InferSchemaWith generalizes the samples to a JSON Schema (draft-07). values
+// of several types have a union `type`, keys present in every object of the
+// same position are `required`, numbers have `minimum` and `maximum`, strings
+// have `minLength` and `maxLength`, and `items` of an array generalizes the
+// elements of all the arrays at the position. strings and integers which take
+// a few distinct values repeatedly are listed as `enum` candidates instead
+func InferSchemaWith(opts SchemaOptions, samples ...*Jzon) *Jzon {
+	var s = newShape()
+	for _, sample := range samples {
+		s.add(sample, opts)
+	}
+
+	res := s.schema(opts)
+	res.Insert("$schema", NewFromAny(SCHEMA_DRAFT))
+
+	return res
+}
@@ -0,0 +1,129 @@
+package jzon
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"hash"
+)
+
+// SignOptions controls how `SignWith()` and `VerifyWith()` sign a node
+type SignOptions struct {
+	// Field embeds the signature as a base64 string in this member of the
+	// object being signed, the member is excluded from the signed content
+	Field string
+
+	// Hash is the hash function of HMAC, it's SHA-256 by default
+	Hash func() hash.Hash
+}
+
+func (opts SignOptions) hash() func() hash.Hash {
+	if opts.Hash == nil {
+		return sha256.New
+	}
+	return opts.Hash
+}
+
+// Sign returns a detached signature over the canonical form of the node, see
+// `Canonical()`, so reordering keys doesn't break it. the key chooses the
+// algorithm: a []byte is a secret of HMAC-SHA256, and an ed25519.PrivateKey
+// signs with Ed25519. if the key is of any other type, or the node contains
+// integers which are not I-JSON, see `CanonicalStrict()`, an error will be thrown out
+func Sign(jz *Jzon, key Any) (sig []byte, err error) {
+	return SignWith(jz, key, SignOptions{})
+}
+
+// Verify reports whether sig is a signature of the node made by `Sign()`, the
+// key is the same secret of HMAC, or the ed25519.PublicKey of the signer. if
+// the key is of an unsupported type, or the node contains integers which are
+// not I-JSON, an error will be thrown out
+func Verify(jz *Jzon, sig []byte, key Any) (ok bool, err error) {
+	return VerifyWith(jz, sig, key, SignOptions{})
+}
+
+// SignWith signs the node as `Sign()` does, with options. if Field is set, the
+// node must be an object, and the signature is embedded in it as well
+func SignWith(jz *Jzon, key Any, opts SignOptions) (sig []byte, err error) {
+	content, err := signedContent(jz, opts)
+	if err != nil {
+		return
+	}
+
+	switch k := key.(type) {
+	case ed25519.PrivateKey:
+		if len(k) != ed25519.PrivateKeySize {
+			return nil, fmt.Errorf("expect a private key of %d bytes, but found %d bytes", ed25519.PrivateKeySize, len(k))
+		}
+		sig = ed25519.Sign(k, content)
+
+	case []byte:
+		mac := hmac.New(opts.hash(), k)
+		mac.Write(content)
+		sig = mac.Sum(nil)
+
+	default:
+		return nil, fmt.Errorf("unsupported key of type %T", key)
+	}
+
+	if opts.Field != "" {
+		jz.data.(map[string]*Jzon)[opts.Field] = NewFromAny(base64.StdEncoding.EncodeToString(sig))
+	}
+
+	return sig, nil
+}
+
+// VerifyWith verifies the node as `Verify()` does, with options. if Field is
+// set and sig is nil, the signature embedded in the field is verified
+func VerifyWith(jz *Jzon, sig []byte, key Any, opts SignOptions) (ok bool, err error) {
+	content, err := signedContent(jz, opts)
+	if err != nil {
+		return
+	}
+
+	if sig == nil && opts.Field != "" {
+		embedded, err := jz.ValueOf(opts.Field)
+		if err != nil {
+			return false, fmt.Errorf("missing signature in member `%s`", opts.Field)
+		}
+		s, err := embedded.String()
+		if err != nil {
+			return false, err
+		}
+		if sig, err = base64.StdEncoding.DecodeString(s); err != nil {
+			return false, fmt.Errorf("malformed signature in member `%s`: %v", opts.Field, err)
+		}
+	}
+
+	switch k := key.(type) {
+	case ed25519.PublicKey:
+		if len(k) != ed25519.PublicKeySize {
+			return false, fmt.Errorf("expect a public key of %d bytes, but found %d bytes", ed25519.PublicKeySize, len(k))
+		}
+		return ed25519.Verify(k, content, sig), nil
+
+	case []byte:
+		mac := hmac.New(opts.hash(), k)
+		mac.Write(content)
+		return hmac.Equal(mac.Sum(nil), sig), nil
+	}
+
+	return false, fmt.Errorf("unsupported key of type %T", key)
+}
+
+// signedContent returns the canonical form of the node, without the field of the signature
+func signedContent(jz *Jzon, opts SignOptions) (content []byte, err error) {
+	if opts.Field == "" {
+		return jz.CanonicalStrict()
+	}
+
+	if jz.Type != JzTypeObj {
+		return nil, expectTypeOf(JzTypeObj, jz.Type)
+	}
+
+	unsigned := jz.Clone()
+	delete(unsigned.data.(map[string]*Jzon), opts.Field)
+
+	return unsigned.CanonicalStrict()
+}
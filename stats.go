@@ -0,0 +1,208 @@
+package jzon
+
+import (
+	"math"
+	"math/bits"
+	"reflect"
+	"sort"
+	"strconv"
+)
+
+// STATS_LARGEST_ARRAYS is the number of arrays listed in `Stats.LargestArrays`
+const STATS_LARGEST_ARRAYS = 5
+
+// Stats is the profile of a node and all its descendants, see `Jzon.Stats()`
+type Stats struct {
+	// Nodes is the number of nodes, including the root node
+	Nodes int
+
+	// Types counts the nodes of each type
+	Types map[ValueType]int
+
+	// MaxDepth is the depth of the deepest node, the root node is at depth 0
+	MaxDepth int
+
+	// Keys counts how many times each key appears in all objects
+	Keys map[string]int
+
+	// StringLengths is a histogram of lengths of strings in bytes, StringLengths[0]
+	// counts empty strings, and StringLengths[i] counts strings of length in
+	// [2^(i-1), 2^i), so lengths of 2 and 3 fall in StringLengths[2]
+	StringLengths []int
+
+	// MinNumber and MaxNumber are the range of integers and floats, both of
+	// them are zero if there is no number
+	MinNumber float64
+	MaxNumber float64
+
+	// MinInt and MaxInt are the exact range of integers, they are the range of
+	// all numbers if there is no float, since MinNumber and MaxNumber lose
+	// precision beyond 2^53
+	MinInt int64
+	MaxInt int64
+
+	// LargestArrays lists the largest arrays in descending order of length,
+	// at most STATS_LARGEST_ARRAYS of them
+	LargestArrays []ArrayStats
+
+	// Memory is a rough estimate in bytes of the memory held by the nodes
+	Memory int
+}
+
+// ArrayStats is the path and the length of an array
+type ArrayStats struct {
+	Path   string
+	Length int
+}
+
+// sizes used by the estimate of memory, a map entry is counted as its key,
+// its value pointer and the overhead of buckets of the map
+var (
+	nodeSize     = int(jzonType.Size())
+	pointerSize  = int(jzonPtrType.Size())
+	stringSize   = int(reflect.TypeOf("").Size())
+	sliceSize    = int(reflect.TypeOf([]*Jzon{}).Size())
+	mapSize      = 48
+	mapEntrySize = stringSize + pointerSize + 8
+)
+
+// Stats walks the node and all its descendants, and profiles them: counts of
+// nodes per type, the maximum depth, the frequency of keys, the histogram of
+// string lengths, the range of numbers, the largest arrays, and an estimate
+// of the memory held by them. see `Stats.Jzon()` for a report as a node
+func (jz *Jzon) Stats() (s *Stats) {
+	s = &Stats{
+		Types: make(map[ValueType]int),
+		Keys:  make(map[string]int),
+	}
+
+	var numbers int
+	jz.Walk(func(path Path, node *Jzon) WalkAction {
+		s.Nodes++
+		s.Types[node.Type]++
+		s.MaxDepth = max(s.MaxDepth, len(path.segs))
+		s.Memory += nodeSize + pointerSize
+
+		if n := len(path.segs); n > 0 && path.segs[n-1].kind == _SegKey {
+			s.Keys[path.segs[n-1].key]++
+		}
+
+		switch node.Type {
+		case JzTypeStr:
+			l := len(node.data.(string))
+			i := bits.Len(uint(l))
+			for len(s.StringLengths) <= i {
+				s.StringLengths = append(s.StringLengths, 0)
+			}
+			s.StringLengths[i]++
+			s.Memory += stringSize + l
+
+		case JzTypeInt, JzTypeFlt:
+			if node.Type == JzTypeInt {
+				n := node.data.(int64)
+				if s.Types[JzTypeInt] == 1 {
+					s.MinInt, s.MaxInt = n, n
+				}
+				s.MinInt, s.MaxInt = min(s.MinInt, n), max(s.MaxInt, n)
+			}
+			f := toFloat(node)
+			if numbers == 0 {
+				s.MinNumber, s.MaxNumber = f, f
+			}
+			s.MinNumber, s.MaxNumber = math.Min(s.MinNumber, f), math.Max(s.MaxNumber, f)
+			s.Memory += 8
+			numbers++
+
+		case JzTypeArr:
+			arr := node.data.([]*Jzon)
+			s.LargestArrays = append(s.LargestArrays, ArrayStats{Path: path.String(), Length: len(arr)})
+			s.Memory += sliceSize + cap(arr)*pointerSize
+
+		case JzTypeObj:
+			m := node.data.(map[string]*Jzon)
+			s.Memory += mapSize
+			for k := range m {
+				s.Memory += mapEntrySize + len(k)
+			}
+		}
+
+		return WalkContinue
+	})
+
+	sort.SliceStable(s.LargestArrays, func(i, j int) bool {
+		return s.LargestArrays[i].Length > s.LargestArrays[j].Length
+	})
+	if len(s.LargestArrays) > STATS_LARGEST_ARRAYS {
+		s.LargestArrays = s.LargestArrays[:STATS_LARGEST_ARRAYS]
+	}
+
+	return s
+}
+
+// Jzon reports the stats as a node, such as:
+//
+//	{
+//	  "nodes": 6, "max_depth": 2, "memory": 512,
+//	  "types": {"object": 1, "array": 1, "integer": 3, "string": 1},
+//	  "keys": {"a": 1, "b": 1},
+//	  "string_lengths": {"0": 0, "1": 0, "2-3": 1},
+//	  "numbers": {"min": 1, "max": 3},
+//	  "largest_arrays": [{"path": "$.a", "length": 3}]
+//	}
+//
+// types are named as they are in JSON Schema, "numbers" are integers unless
+// there is a float, and they are left out if there is no number
+func (s *Stats) Jzon() *Jzon {
+	var res = New(JzTypeObj)
+	res.Insert("nodes", NewFromAny(s.Nodes))
+	res.Insert("max_depth", NewFromAny(s.MaxDepth))
+	res.Insert("memory", NewFromAny(s.Memory))
+
+	types := New(JzTypeObj)
+	for t, n := range s.Types {
+		types.Insert(typeNames[t], NewFromAny(n))
+	}
+	res.Insert("types", types)
+
+	keys := New(JzTypeObj)
+	for k, n := range s.Keys {
+		keys.Insert(k, NewFromAny(n))
+	}
+	res.Insert("keys", keys)
+
+	lengths := New(JzTypeObj)
+	for i, n := range s.StringLengths {
+		var bucket string
+		switch {
+		case i <= 1:
+			bucket = strconv.Itoa(i)
+		default:
+			bucket = strconv.Itoa(1<<(i-1)) + "-" + strconv.Itoa(1<<i-1)
+		}
+		lengths.Insert(bucket, NewFromAny(n))
+	}
+	res.Insert("string_lengths", lengths)
+
+	if s.Types[JzTypeInt]+s.Types[JzTypeFlt] > 0 {
+		numbers := New(JzTypeObj)
+		if s.Types[JzTypeFlt] == 0 {
+			numbers.Insert("min", NewFromAny(s.MinInt))
+			numbers.Insert("max", NewFromAny(s.MaxInt))
+		} else {
+			numbers.Insert("min", NewFromAny(s.MinNumber))
+			numbers.Insert("max", NewFromAny(s.MaxNumber))
+		}
+		res.Insert("numbers", numbers)
+	}
+
+	arrays := New(JzTypeArr)
+	for _, a := range s.LargestArrays {
+		arr := New(JzTypeObj)
+		arr.Insert("path", NewFromAny(a.Path))
+		arr.Insert("length", NewFromAny(a.Length))
+		arrays.Append(arr)
+	}
+	res.Insert("largest_arrays", arrays)
+
+	return res
+}
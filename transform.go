@@ -0,0 +1,1571 @@
+package jzon
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Transform runs a program written in a subset of the jq language against
+// the input node, and returns all values it outputs. the supported subset:
+//
+//	.  .a  ."a"  .[1]  .[1:3]  .[]  ..  ?       paths and iterations
+//	|  ,  //  and  or                           pipes and combinators
+//	+  -  *  /  %  ==  !=  <  <=  >  >=         arithmetic and comparison
+//	[ ... ]  { a, "b": ..., (expr): ... }       array and object construction
+//	if ... then ... elif ... else ... end       conditions
+//	"text \(expr)"                              string interpolation
+//	select(f) map(f) keys length to_entries from_entries with_entries(f)
+//	empty not type add has(k) tostring tonumber sort reverse
+//
+// the input node is never modified, while the outputs may share nodes with it
+func Transform(program string, input *Jzon) (outs []*Jzon, err error) {
+	expr, err := compileTransform(program)
+	if err != nil {
+		return
+	}
+
+	return expr.eval(input)
+}
+
+func compileTransform(program string) (expr jqExpr, err error) {
+	tokens, err := lexTransform(program)
+	if err != nil {
+		return
+	}
+
+	p := &jqParser{tokens: tokens}
+	if p.peek().kind == _TokEOF {
+		return jqIdentity{}, nil
+	}
+
+	expr, err = p.parsePipe(false)
+	if err != nil {
+		return
+	}
+
+	if tok := p.peek(); tok.kind != _TokEOF {
+		return nil, expectToken("end of program", tok)
+	}
+
+	return expr, nil
+}
+
+// tokKind indicates the kind of a token of the transform language
+type tokKind int
+
+const (
+	_TokEOF    tokKind = iota
+	_TokPunct          // . .. [ ] { } ( ) | , : ; ? + - * / % == != < <= > >= //
+	_TokField          // .name
+	_TokIdent          // name, keyword
+	_TokNumber         // 1.5e3
+	_TokString         // "text \(expr)"
+)
+
+// jqStrPart is a piece of a string token, either literal text or an interpolated program
+type jqStrPart struct {
+	text   string
+	interp bool
+}
+
+type jqToken struct {
+	kind  tokKind
+	text  string
+	pos   int
+	parts []jqStrPart
+}
+
+func expectToken(ex string, found jqToken) error {
+	if found.kind == _TokEOF {
+		return fmt.Errorf("expect %s but found end of program at %d", ex, found.pos)
+	}
+	return fmt.Errorf("expect %s but found `%s` at %d", ex, found.text, found.pos)
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+func isIdentChar(c byte) bool {
+	return isIdentStart(c) || isDigit(c)
+}
+
+func lexTransform(src string) (tokens []jqToken, err error) {
+	var i = 0
+	for i < len(src) {
+		c := src[i]
+		start := i
+
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+			continue
+
+		case c == '#':
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+			continue
+
+		case c == '.' && i+1 < len(src) && src[i+1] == '.':
+			i += 2
+			tokens = append(tokens, jqToken{kind: _TokPunct, text: "..", pos: start})
+
+		case c == '.' && i+1 < len(src) && isIdentStart(src[i+1]):
+			i++
+			for i < len(src) && isIdentChar(src[i]) {
+				i++
+			}
+			tokens = append(tokens, jqToken{kind: _TokField, text: src[start+1 : i], pos: start})
+
+		case isDigit(c) || c == '.' && i+1 < len(src) && isDigit(src[i+1]):
+			for i < len(src) && (isNumericChar(src[i])) {
+				if (src[i] == '+' || src[i] == '-') && src[i-1] != 'e' && src[i-1] != 'E' {
+					break
+				}
+				i++
+			}
+			tokens = append(tokens, jqToken{kind: _TokNumber, text: src[start:i], pos: start})
+
+		case isIdentStart(c):
+			for i < len(src) && isIdentChar(src[i]) {
+				i++
+			}
+			tokens = append(tokens, jqToken{kind: _TokIdent, text: src[start:i], pos: start})
+
+		case c == '"':
+			var parts []jqStrPart
+			parts, i, err = lexString(src, i)
+			if err != nil {
+				return
+			}
+			tokens = append(tokens, jqToken{kind: _TokString, text: src[start:i], pos: start, parts: parts})
+
+		default:
+			var op string
+			for _, p := range []string{"==", "!=", "<=", ">=", "//"} {
+				if strings.HasPrefix(src[i:], p) {
+					op = p
+				}
+			}
+			if op == "" && strings.IndexByte(".[]{}()|,:;?+-*/%<>", c) >= 0 {
+				op = string(c)
+			}
+			if op == "" {
+				return nil, fmt.Errorf("unexpected character '%c' at %d", c, i)
+			}
+			i += len(op)
+			tokens = append(tokens, jqToken{kind: _TokPunct, text: op, pos: start})
+		}
+	}
+
+	return append(tokens, jqToken{kind: _TokEOF, pos: len(src)}), nil
+}
+
+// lexString scans a string literal beginning at src[i], the source of each
+// interpolation `\(...)` is kept raw and will be compiled by the parser
+func lexString(src string, i int) (parts []jqStrPart, end int, err error) {
+	var buf []byte
+	var start = i
+
+	i++
+	for {
+		if i >= len(src) {
+			return nil, i, fmt.Errorf("unterminated string beginning at %d", start)
+		}
+
+		switch c := src[i]; {
+		case c == '"':
+			if len(buf) > 0 || len(parts) == 0 {
+				parts = append(parts, jqStrPart{text: string(buf)})
+			}
+			return parts, i + 1, nil
+
+		case c == '\\' && i+1 < len(src) && src[i+1] == '(':
+			if len(buf) > 0 {
+				parts = append(parts, jqStrPart{text: string(buf)})
+				buf = buf[:0]
+			}
+
+			depth := 1
+			j := i + 2
+			for ; j < len(src) && depth > 0; j++ {
+				switch src[j] {
+				case '(':
+					depth++
+				case ')':
+					depth--
+				case '"':
+					if _, j, err = lexString(src, j); err != nil {
+						return
+					}
+					j--
+				}
+			}
+			if depth > 0 {
+				return nil, j, fmt.Errorf("unterminated interpolation beginning at %d", i)
+			}
+			parts = append(parts, jqStrPart{text: src[i+2 : j-1], interp: true})
+			i = j
+
+		case c == '\\' && i+1 < len(src) && src[i+1] == 'u':
+			// unlike parseUnicode() of the parser, it checks the bounds and keeps no state
+			esc := []byte(src[i:])
+			var rem []byte
+			if buf, rem, err = parsePathEscape(buf, esc); err != nil {
+				return nil, i, fmt.Errorf("%v at %d", err, i)
+			}
+			i += len(esc) - len(rem)
+
+		case c == '\\' && i+1 < len(src):
+			escaped, ok := escapeMap[src[i+1]]
+			if !ok {
+				return nil, i, fmt.Errorf("invalid escape '\\%c' at %d", src[i+1], i)
+			}
+			buf = append(buf, escaped)
+			i += 2
+
+		default:
+			buf = append(buf, c)
+			i++
+		}
+	}
+}
+
+type jqParser struct {
+	tokens []jqToken
+	curr   int
+}
+
+func (p *jqParser) peek() jqToken {
+	return p.tokens[p.curr]
+}
+
+func (p *jqParser) next() jqToken {
+	tok := p.tokens[p.curr]
+	if tok.kind != _TokEOF {
+		p.curr++
+	}
+	return tok
+}
+
+func (p *jqParser) is(kind tokKind, text string) bool {
+	tok := p.peek()
+	return tok.kind == kind && tok.text == text
+}
+
+func (p *jqParser) accept(kind tokKind, text string) bool {
+	if p.is(kind, text) {
+		p.next()
+		return true
+	}
+	return false
+}
+
+func (p *jqParser) expect(kind tokKind, text string) error {
+	if !p.accept(kind, text) {
+		return expectToken("`"+text+"`", p.peek())
+	}
+	return nil
+}
+
+// parsePipe parses `a | b`, which has the lowest precedence, if noComma
+// is set, the comma is treated as a delimiter, as in object construction
+func (p *jqParser) parsePipe(noComma bool) (expr jqExpr, err error) {
+	left, err := p.parseComma(noComma)
+	if err != nil {
+		return
+	}
+
+	if !p.accept(_TokPunct, "|") {
+		return left, nil
+	}
+
+	right, err := p.parsePipe(noComma)
+	if err != nil {
+		return
+	}
+
+	return jqPipe{left, right}, nil
+}
+
+func (p *jqParser) parseComma(noComma bool) (expr jqExpr, err error) {
+	expr, err = p.parseAlternative()
+	if err != nil {
+		return
+	}
+
+	for !noComma && p.accept(_TokPunct, ",") {
+		var right jqExpr
+		if right, err = p.parseAlternative(); err != nil {
+			return
+		}
+		expr = jqComma{expr, right}
+	}
+
+	return
+}
+
+func (p *jqParser) parseAlternative() (expr jqExpr, err error) {
+	left, err := p.parseBinary(0)
+	if err != nil {
+		return
+	}
+
+	if !p.accept(_TokPunct, "//") {
+		return left, nil
+	}
+
+	right, err := p.parseAlternative()
+	if err != nil {
+		return
+	}
+
+	return jqAlternative{left, right}, nil
+}
+
+// binaryLevels lists binary operators from the lowest precedence to the highest
+var binaryLevels = [][]string{
+	{"or"},
+	{"and"},
+	{"==", "!=", "<", "<=", ">", ">="},
+	{"+", "-"},
+	{"*", "/", "%"},
+}
+
+func (p *jqParser) binaryOp(level int) (op string, ok bool) {
+	tok := p.peek()
+	if tok.kind != _TokPunct && tok.kind != _TokIdent {
+		return
+	}
+
+	for _, op := range binaryLevels[level] {
+		if tok.text == op {
+			return op, true
+		}
+	}
+
+	return
+}
+
+func (p *jqParser) parseBinary(level int) (expr jqExpr, err error) {
+	if level == len(binaryLevels) {
+		return p.parseUnary()
+	}
+
+	expr, err = p.parseBinary(level + 1)
+	if err != nil {
+		return
+	}
+
+	for {
+		op, ok := p.binaryOp(level)
+		if !ok {
+			return
+		}
+		p.next()
+
+		var right jqExpr
+		if right, err = p.parseBinary(level + 1); err != nil {
+			return
+		}
+		expr = jqBinary{op, expr, right}
+	}
+}
+
+func (p *jqParser) parseUnary() (expr jqExpr, err error) {
+	if p.accept(_TokPunct, "-") {
+		if expr, err = p.parseUnary(); err != nil {
+			return
+		}
+		return jqBinary{"-", jqLiteral{NewFromAny(0)}, expr}, nil
+	}
+
+	return p.parsePostfix()
+}
+
+func (p *jqParser) parsePostfix() (expr jqExpr, err error) {
+	expr, err = p.parsePrimary()
+	if err != nil {
+		return
+	}
+
+	for {
+		tok := p.peek()
+		switch {
+		case tok.kind == _TokField:
+			p.next()
+			expr = jqIndex{expr, jqLiteral{NewFromAny(tok.text)}}
+
+		case tok.kind == _TokPunct && tok.text == "." && p.tokens[p.curr+1].kind == _TokString:
+			p.next()
+			var key jqExpr
+			if key, err = p.parseString(p.next()); err != nil {
+				return
+			}
+			expr = jqIndex{expr, key}
+
+		case tok.kind == _TokPunct && tok.text == "." && p.tokens[p.curr+1].text == "[":
+			p.next()
+
+		case tok.kind == _TokPunct && tok.text == "[":
+			if expr, err = p.parseBracket(expr); err != nil {
+				return
+			}
+
+		case tok.kind == _TokPunct && tok.text == "?":
+			p.next()
+			expr = jqTry{expr}
+
+		default:
+			return
+		}
+	}
+}
+
+// parseBracket parses the suffix `[]`, `[expr]` or `[from:to]` of target
+func (p *jqParser) parseBracket(target jqExpr) (expr jqExpr, err error) {
+	if err = p.expect(_TokPunct, "["); err != nil {
+		return
+	}
+
+	if p.accept(_TokPunct, "]") {
+		return jqIterate{target}, nil
+	}
+
+	var from, to jqExpr
+	if !p.is(_TokPunct, ":") {
+		if from, err = p.parsePipe(false); err != nil {
+			return
+		}
+	}
+
+	if !p.accept(_TokPunct, ":") {
+		if err = p.expect(_TokPunct, "]"); err != nil {
+			return
+		}
+		return jqIndex{target, from}, nil
+	}
+
+	if !p.is(_TokPunct, "]") {
+		if to, err = p.parsePipe(false); err != nil {
+			return
+		}
+	}
+
+	if err = p.expect(_TokPunct, "]"); err != nil {
+		return
+	}
+
+	return jqSlice{target, from, to}, nil
+}
+
+func (p *jqParser) parsePrimary() (expr jqExpr, err error) {
+	tok := p.peek()
+
+	switch tok.kind {
+	case _TokField:
+		p.next()
+		return jqIndex{jqIdentity{}, jqLiteral{NewFromAny(tok.text)}}, nil
+
+	case _TokNumber:
+		p.next()
+		return parseNumberLiteral(tok)
+
+	case _TokString:
+		p.next()
+		return p.parseString(tok)
+
+	case _TokIdent:
+		return p.parseIdent()
+
+	case _TokPunct:
+		switch tok.text {
+		case ".":
+			p.next()
+			if p.peek().kind == _TokString {
+				var key jqExpr
+				if key, err = p.parseString(p.next()); err != nil {
+					return
+				}
+				return jqIndex{jqIdentity{}, key}, nil
+			}
+			return jqIdentity{}, nil
+
+		case "..":
+			p.next()
+			return jqRecurse{}, nil
+
+		case "(":
+			p.next()
+			if expr, err = p.parsePipe(false); err != nil {
+				return
+			}
+			return expr, p.expect(_TokPunct, ")")
+
+		case "[":
+			p.next()
+			if p.accept(_TokPunct, "]") {
+				return jqArray{}, nil
+			}
+			if expr, err = p.parsePipe(false); err != nil {
+				return
+			}
+			return jqArray{expr}, p.expect(_TokPunct, "]")
+
+		case "{":
+			return p.parseObject()
+		}
+	}
+
+	return nil, expectToken("a term", tok)
+}
+
+func parseNumberLiteral(tok jqToken) (expr jqExpr, err error) {
+	if n, e := strconv.ParseInt(tok.text, 10, 64); e == nil {
+		return jqLiteral{NewFromAny(n)}, nil
+	}
+
+	f, e := strconv.ParseFloat(tok.text, 64)
+	if e != nil {
+		return nil, fmt.Errorf("invalid number `%s` at %d", tok.text, tok.pos)
+	}
+
+	return jqLiteral{NewFromAny(f)}, nil
+}
+
+func (p *jqParser) parseString(tok jqToken) (expr jqExpr, err error) {
+	if len(tok.parts) == 1 && !tok.parts[0].interp {
+		return jqLiteral{NewFromAny(tok.parts[0].text)}, nil
+	}
+
+	var parts []jqExpr
+	for _, part := range tok.parts {
+		if !part.interp {
+			parts = append(parts, jqLiteral{NewFromAny(part.text)})
+			continue
+		}
+
+		var sub jqExpr
+		if sub, err = compileTransform(part.text); err != nil {
+			return nil, fmt.Errorf("in interpolation of string at %d: %v", tok.pos, err)
+		}
+		parts = append(parts, sub)
+	}
+
+	return jqFormat{parts}, nil
+}
+
+func (p *jqParser) parseIdent() (expr jqExpr, err error) {
+	tok := p.next()
+
+	switch tok.text {
+	case "true":
+		return jqLiteral{NewFromAny(true)}, nil
+	case "false":
+		return jqLiteral{NewFromAny(false)}, nil
+	case "null":
+		return jqLiteral{New(JzTypeNul)}, nil
+	case "if":
+		return p.parseIf()
+	}
+
+	var args []jqExpr
+	if p.accept(_TokPunct, "(") {
+		for {
+			var arg jqExpr
+			if arg, err = p.parsePipe(false); err != nil {
+				return
+			}
+			args = append(args, arg)
+
+			if p.accept(_TokPunct, ";") {
+				continue
+			}
+			if err = p.expect(_TokPunct, ")"); err != nil {
+				return
+			}
+			break
+		}
+	}
+
+	fn, ok := jqBuiltins[tok.text]
+	if !ok || fn.nArgs != len(args) {
+		return nil, fmt.Errorf("function %s/%d is not defined at %d", tok.text, len(args), tok.pos)
+	}
+
+	return jqCall{tok.text, args}, nil
+}
+
+// parseIf parses the rest of `if c then a elif c then b else d end`
+func (p *jqParser) parseIf() (expr jqExpr, err error) {
+	var cond, then, otherwise jqExpr
+
+	if cond, err = p.parsePipe(false); err != nil {
+		return
+	}
+	if err = p.expect(_TokIdent, "then"); err != nil {
+		return
+	}
+	if then, err = p.parsePipe(false); err != nil {
+		return
+	}
+
+	switch {
+	case p.accept(_TokIdent, "elif"):
+		if otherwise, err = p.parseIf(); err != nil {
+			return
+		}
+		return jqIf{cond, then, otherwise}, nil
+
+	case p.accept(_TokIdent, "else"):
+		if otherwise, err = p.parsePipe(false); err != nil {
+			return
+		}
+	}
+
+	return jqIf{cond, then, otherwise}, p.expect(_TokIdent, "end")
+}
+
+func (p *jqParser) parseObject() (expr jqExpr, err error) {
+	var obj jqObject
+
+	if err = p.expect(_TokPunct, "{"); err != nil {
+		return
+	}
+
+	for !p.accept(_TokPunct, "}") {
+		var key, value jqExpr
+		tok := p.next()
+
+		switch {
+		case tok.kind == _TokIdent:
+			key = jqLiteral{NewFromAny(tok.text)}
+			value = jqIndex{jqIdentity{}, key}
+
+		case tok.kind == _TokString:
+			if key, err = p.parseString(tok); err != nil {
+				return
+			}
+			value = jqIndex{jqIdentity{}, key}
+
+		case tok.kind == _TokPunct && tok.text == "(":
+			if key, err = p.parsePipe(false); err != nil {
+				return
+			}
+			if err = p.expect(_TokPunct, ")"); err != nil {
+				return
+			}
+			value = nil
+
+		default:
+			return nil, expectToken("an object key", tok)
+		}
+
+		if p.accept(_TokPunct, ":") {
+			if value, err = p.parsePipe(true); err != nil {
+				return
+			}
+		} else if value == nil {
+			return nil, expectToken("`:`", p.peek())
+		}
+
+		obj.keys = append(obj.keys, key)
+		obj.values = append(obj.values, value)
+
+		if !p.is(_TokPunct, "}") {
+			if err = p.expect(_TokPunct, ","); err != nil {
+				return
+			}
+		}
+	}
+
+	return obj, nil
+}
+
+// jqExpr is a compiled expression of the transform language, for each
+// input an expression generates zero, one or more outputs
+type jqExpr interface {
+	eval(in *Jzon) ([]*Jzon, error)
+}
+
+type jqIdentity struct{}
+
+func (jqIdentity) eval(in *Jzon) ([]*Jzon, error) {
+	return []*Jzon{in}, nil
+}
+
+type jqLiteral struct {
+	value *Jzon
+}
+
+func (e jqLiteral) eval(in *Jzon) ([]*Jzon, error) {
+	return []*Jzon{e.value}, nil
+}
+
+type jqPipe struct {
+	left, right jqExpr
+}
+
+func (e jqPipe) eval(in *Jzon) (outs []*Jzon, err error) {
+	lefts, err := e.left.eval(in)
+	if err != nil {
+		return
+	}
+
+	for _, l := range lefts {
+		var rights []*Jzon
+		if rights, err = e.right.eval(l); err != nil {
+			return
+		}
+		outs = append(outs, rights...)
+	}
+
+	return
+}
+
+type jqComma struct {
+	left, right jqExpr
+}
+
+func (e jqComma) eval(in *Jzon) (outs []*Jzon, err error) {
+	if outs, err = e.left.eval(in); err != nil {
+		return
+	}
+
+	rights, err := e.right.eval(in)
+	return append(outs, rights...), err
+}
+
+type jqAlternative struct {
+	left, right jqExpr
+}
+
+func (e jqAlternative) eval(in *Jzon) (outs []*Jzon, err error) {
+	lefts, _ := e.left.eval(in)
+	for _, l := range lefts {
+		if isTruthy(l) {
+			outs = append(outs, l)
+		}
+	}
+
+	if len(outs) > 0 {
+		return
+	}
+
+	return e.right.eval(in)
+}
+
+type jqTry struct {
+	body jqExpr
+}
+
+func (e jqTry) eval(in *Jzon) ([]*Jzon, error) {
+	outs, _ := e.body.eval(in)
+	return outs, nil
+}
+
+type jqRecurse struct{}
+
+func (jqRecurse) eval(in *Jzon) (outs []*Jzon, err error) {
+	outs = append(outs, in)
+	for _, child := range in.children() {
+		var sub []*Jzon
+		sub, _ = jqRecurse{}.eval(child)
+		outs = append(outs, sub...)
+	}
+
+	return
+}
+
+type jqIndex struct {
+	target, index jqExpr
+}
+
+func (e jqIndex) eval(in *Jzon) (outs []*Jzon, err error) {
+	targets, err := e.target.eval(in)
+	if err != nil {
+		return
+	}
+
+	indices, err := e.index.eval(in)
+	if err != nil {
+		return
+	}
+
+	for _, t := range targets {
+		for _, i := range indices {
+			var v *Jzon
+			if v, err = indexValue(t, i); err != nil {
+				return
+			}
+			outs = append(outs, v)
+		}
+	}
+
+	return
+}
+
+func indexValue(t, i *Jzon) (v *Jzon, err error) {
+	switch {
+	case t.Type == JzTypeNul && (i.Type == JzTypeStr || isNumber(i)):
+		return New(JzTypeNul), nil
+
+	case t.Type == JzTypeObj && i.Type == JzTypeStr:
+		if v, err = t.ValueOf(i.data.(string)); err != nil {
+			return New(JzTypeNul), nil
+		}
+		return v, nil
+
+	case t.Type == JzTypeArr && isNumber(i):
+		arr := t.data.([]*Jzon)
+		n := int(math.Floor(toFloat(i)))
+		if n < 0 {
+			n += len(arr)
+		}
+		if n < 0 || n >= len(arr) {
+			return New(JzTypeNul), nil
+		}
+		return arr[n], nil
+	}
+
+	return nil, fmt.Errorf("cannot index %s with %s", jqTypeName(t), jqTypeName(i))
+}
+
+type jqSlice struct {
+	target, from, to jqExpr
+}
+
+func (e jqSlice) eval(in *Jzon) (outs []*Jzon, err error) {
+	targets, err := e.target.eval(in)
+	if err != nil {
+		return
+	}
+
+	var froms, tos = []*Jzon{New(JzTypeNul)}, []*Jzon{New(JzTypeNul)}
+	if e.from != nil {
+		if froms, err = e.from.eval(in); err != nil {
+			return
+		}
+	}
+	if e.to != nil {
+		if tos, err = e.to.eval(in); err != nil {
+			return
+		}
+	}
+
+	for _, t := range targets {
+		for _, from := range froms {
+			for _, to := range tos {
+				var v *Jzon
+				if v, err = sliceValue(t, from, to); err != nil {
+					return
+				}
+				outs = append(outs, v)
+			}
+		}
+	}
+
+	return
+}
+
+func sliceValue(t, from, to *Jzon) (v *Jzon, err error) {
+	var length int
+	switch t.Type {
+	case JzTypeNul:
+		return t, nil
+	case JzTypeArr:
+		length = len(t.data.([]*Jzon))
+	case JzTypeStr:
+		length = utf8.RuneCountInString(t.data.(string))
+	default:
+		return nil, fmt.Errorf("cannot slice %s", jqTypeName(t))
+	}
+
+	bound := func(b *Jzon, def int) (int, error) {
+		if b.Type == JzTypeNul {
+			return def, nil
+		}
+		if !isNumber(b) {
+			return 0, fmt.Errorf("slice indices must be numbers, but found %s", jqTypeName(b))
+		}
+		n := int(math.Floor(toFloat(b)))
+		if n < 0 {
+			n += length
+		}
+		if n < 0 {
+			n = 0
+		}
+		if n > length {
+			n = length
+		}
+		return n, nil
+	}
+
+	start, err := bound(from, 0)
+	if err != nil {
+		return
+	}
+	end, err := bound(to, length)
+	if err != nil {
+		return
+	}
+	if end < start {
+		end = start
+	}
+
+	if t.Type == JzTypeStr {
+		runes := []rune(t.data.(string))
+		return NewFromAny(string(runes[start:end])), nil
+	}
+
+	sliced := make([]*Jzon, end-start)
+	copy(sliced, t.data.([]*Jzon)[start:end])
+	return NewFromAny(sliced), nil
+}
+
+type jqIterate struct {
+	target jqExpr
+}
+
+func (e jqIterate) eval(in *Jzon) (outs []*Jzon, err error) {
+	targets, err := e.target.eval(in)
+	if err != nil {
+		return
+	}
+
+	for _, t := range targets {
+		if t.Type != JzTypeArr && t.Type != JzTypeObj {
+			return nil, fmt.Errorf("cannot iterate over %s", jqTypeName(t))
+		}
+		outs = append(outs, t.children()...)
+	}
+
+	return
+}
+
+type jqArray struct {
+	body jqExpr
+}
+
+func (e jqArray) eval(in *Jzon) (outs []*Jzon, err error) {
+	var elems = make([]*Jzon, 0)
+	if e.body != nil {
+		if elems, err = e.body.eval(in); err != nil {
+			return
+		}
+	}
+
+	return []*Jzon{NewFromAny(append(make([]*Jzon, 0, len(elems)), elems...))}, nil
+}
+
+type jqObject struct {
+	keys   []jqExpr
+	values []jqExpr
+}
+
+func (e jqObject) eval(in *Jzon) (outs []*Jzon, err error) {
+	// every combination of keys and values generates an object
+	var partials = []map[string]*Jzon{{}}
+	for i := range e.keys {
+		var ks, vs []*Jzon
+		if ks, err = e.keys[i].eval(in); err != nil {
+			return
+		}
+		if vs, err = e.values[i].eval(in); err != nil {
+			return
+		}
+
+		var next []map[string]*Jzon
+		for _, partial := range partials {
+			for _, k := range ks {
+				if k.Type != JzTypeStr {
+					return nil, fmt.Errorf("object keys must be strings, but found %s", jqTypeName(k))
+				}
+				for _, v := range vs {
+					m := make(map[string]*Jzon, len(partial)+1)
+					for pk, pv := range partial {
+						m[pk] = pv
+					}
+					m[k.data.(string)] = v
+					next = append(next, m)
+				}
+			}
+		}
+		partials = next
+	}
+
+	for _, m := range partials {
+		outs = append(outs, NewFromAny(m))
+	}
+
+	return
+}
+
+type jqFormat struct {
+	parts []jqExpr
+}
+
+func (e jqFormat) eval(in *Jzon) (outs []*Jzon, err error) {
+	var partials = []string{""}
+	for _, part := range e.parts {
+		var vs []*Jzon
+		if vs, err = part.eval(in); err != nil {
+			return
+		}
+
+		var next []string
+		for _, partial := range partials {
+			for _, v := range vs {
+				next = append(next, partial+jqToString(v))
+			}
+		}
+		partials = next
+	}
+
+	for _, s := range partials {
+		outs = append(outs, NewFromAny(s))
+	}
+
+	return
+}
+
+type jqIf struct {
+	cond, then, otherwise jqExpr
+}
+
+func (e jqIf) eval(in *Jzon) (outs []*Jzon, err error) {
+	conds, err := e.cond.eval(in)
+	if err != nil {
+		return
+	}
+
+	for _, c := range conds {
+		var branch []*Jzon
+		switch {
+		case isTruthy(c):
+			branch, err = e.then.eval(in)
+		case e.otherwise != nil:
+			branch, err = e.otherwise.eval(in)
+		default:
+			branch = []*Jzon{in}
+		}
+		if err != nil {
+			return
+		}
+		outs = append(outs, branch...)
+	}
+
+	return
+}
+
+type jqBinary struct {
+	op          string
+	left, right jqExpr
+}
+
+func (e jqBinary) eval(in *Jzon) (outs []*Jzon, err error) {
+	if e.op == "and" || e.op == "or" {
+		return e.evalLogical(in)
+	}
+
+	rights, err := e.right.eval(in)
+	if err != nil {
+		return
+	}
+
+	lefts, err := e.left.eval(in)
+	if err != nil {
+		return
+	}
+
+	for _, r := range rights {
+		for _, l := range lefts {
+			var v *Jzon
+			if v, err = binaryValue(e.op, l, r); err != nil {
+				return
+			}
+			outs = append(outs, v)
+		}
+	}
+
+	return
+}
+
+func (e jqBinary) evalLogical(in *Jzon) (outs []*Jzon, err error) {
+	lefts, err := e.left.eval(in)
+	if err != nil {
+		return
+	}
+
+	for _, l := range lefts {
+		if isTruthy(l) == (e.op == "or") {
+			outs = append(outs, NewFromAny(isTruthy(l)))
+			continue
+		}
+
+		var rights []*Jzon
+		if rights, err = e.right.eval(in); err != nil {
+			return
+		}
+		for _, r := range rights {
+			outs = append(outs, NewFromAny(isTruthy(r)))
+		}
+	}
+
+	return
+}
+
+func binaryValue(op string, l, r *Jzon) (v *Jzon, err error) {
+	switch op {
+	case "==":
+		return NewFromAny(Compare(l, r) == 0), nil
+	case "!=":
+		return NewFromAny(Compare(l, r) != 0), nil
+	case "<":
+		return NewFromAny(Compare(l, r) < 0), nil
+	case "<=":
+		return NewFromAny(Compare(l, r) <= 0), nil
+	case ">":
+		return NewFromAny(Compare(l, r) > 0), nil
+	case ">=":
+		return NewFromAny(Compare(l, r) >= 0), nil
+	}
+
+	switch {
+	case isNumber(l) && isNumber(r):
+		return arithmetic(op, l, r)
+
+	case op == "+" && l.Type == JzTypeNul:
+		return r, nil
+
+	case op == "+" && r.Type == JzTypeNul:
+		return l, nil
+
+	case op == "+" && l.Type == JzTypeStr && r.Type == JzTypeStr:
+		return NewFromAny(l.data.(string) + r.data.(string)), nil
+
+	case op == "+" && l.Type == JzTypeArr && r.Type == JzTypeArr:
+		la, ra := l.data.([]*Jzon), r.data.([]*Jzon)
+		return NewFromAny(append(append(make([]*Jzon, 0, len(la)+len(ra)), la...), ra...)), nil
+
+	case op == "+" && l.Type == JzTypeObj && r.Type == JzTypeObj:
+		m := make(map[string]*Jzon)
+		for k, v := range l.data.(map[string]*Jzon) {
+			m[k] = v
+		}
+		for k, v := range r.data.(map[string]*Jzon) {
+			m[k] = v
+		}
+		return NewFromAny(m), nil
+
+	case op == "-" && l.Type == JzTypeArr && r.Type == JzTypeArr:
+		res, _ := l.AFilter(func(a *Jzon) bool {
+			for _, b := range r.data.([]*Jzon) {
+				if Compare(a, b) == 0 {
+					return false
+				}
+			}
+			return true
+		})
+		return NewFromAny(res), nil
+
+	case op == "/" && l.Type == JzTypeStr && r.Type == JzTypeStr:
+		var parts = make([]*Jzon, 0)
+		if l.data.(string) != "" {
+			for _, s := range strings.Split(l.data.(string), r.data.(string)) {
+				parts = append(parts, NewFromAny(s))
+			}
+		}
+		return NewFromAny(parts), nil
+	}
+
+	return nil, fmt.Errorf("%s (%s) and %s (%s) cannot be applied with `%s`",
+		jqTypeName(l), l.Compact(), jqTypeName(r), r.Compact(), op)
+}
+
+// arithmetic keeps the result an integer as long as both operands are
+// integers and the result is exact, otherwise the result is a float
+func arithmetic(op string, l, r *Jzon) (v *Jzon, err error) {
+	if l.Type == JzTypeInt && r.Type == JzTypeInt {
+		a, b := l.data.(int64), r.data.(int64)
+		switch {
+		case op == "+":
+			return NewFromAny(a + b), nil
+		case op == "-":
+			return NewFromAny(a - b), nil
+		case op == "*":
+			return NewFromAny(a * b), nil
+		case (op == "/" || op == "%") && b == 0:
+			return nil, fmt.Errorf("%d cannot be divided by zero", a)
+		case op == "/" && a%b == 0:
+			return NewFromAny(a / b), nil
+		case op == "%":
+			return NewFromAny(a % b), nil
+		}
+	}
+
+	a, b := toFloat(l), toFloat(r)
+	switch op {
+	case "+":
+		return NewFromAny(a + b), nil
+	case "-":
+		return NewFromAny(a - b), nil
+	case "*":
+		return NewFromAny(a * b), nil
+	case "/":
+		if b == 0 {
+			return nil, fmt.Errorf("%v cannot be divided by zero", a)
+		}
+		return NewFromAny(a / b), nil
+	case "%":
+		if int64(b) == 0 {
+			return nil, fmt.Errorf("%v cannot be divided by zero", a)
+		}
+		return NewFromAny(int64(a) % int64(b)), nil
+	}
+
+	return nil, fmt.Errorf("unknown operator `%s`", op)
+}
+
+type jqCall struct {
+	name string
+	args []jqExpr
+}
+
+func (e jqCall) eval(in *Jzon) ([]*Jzon, error) {
+	return jqBuiltins[e.name].fn(in, e.args)
+}
+
+type jqBuiltin struct {
+	nArgs int
+	fn    func(in *Jzon, args []jqExpr) ([]*Jzon, error)
+}
+
+var jqBuiltins map[string]jqBuiltin
+
+func init() {
+	// initialized in init() since some builtins refer to the table itself
+	jqBuiltins = map[string]jqBuiltin{
+		"empty":        {0, func(in *Jzon, args []jqExpr) ([]*Jzon, error) { return nil, nil }},
+		"not":          {0, jqSingle(func(in *Jzon) (*Jzon, error) { return NewFromAny(!isTruthy(in)), nil })},
+		"type":         {0, jqSingle(func(in *Jzon) (*Jzon, error) { return NewFromAny(jqTypeName(in)), nil })},
+		"length":       {0, jqSingle(jqLength)},
+		"keys":         {0, jqSingle(jqKeys)},
+		"add":          {0, jqSingle(jqAdd)},
+		"tostring":     {0, jqSingle(func(in *Jzon) (*Jzon, error) { return NewFromAny(jqToString(in)), nil })},
+		"tonumber":     {0, jqSingle(jqToNumber)},
+		"sort":         {0, jqSingle(jqSort)},
+		"reverse":      {0, jqSingle(jqReverse)},
+		"to_entries":   {0, jqSingle(jqToEntries)},
+		"from_entries": {0, jqSingle(jqFromEntries)},
+		"select":       {1, jqSelect},
+		"map":          {1, jqMap},
+		"has":          {1, jqHas},
+		"with_entries": {1, jqWithEntries},
+	}
+}
+
+// jqSingle lifts a function with exactly one output to a builtin
+func jqSingle(fn func(in *Jzon) (*Jzon, error)) func(*Jzon, []jqExpr) ([]*Jzon, error) {
+	return func(in *Jzon, args []jqExpr) ([]*Jzon, error) {
+		v, err := fn(in)
+		if err != nil {
+			return nil, err
+		}
+		return []*Jzon{v}, nil
+	}
+}
+
+func jqLength(in *Jzon) (v *Jzon, err error) {
+	switch in.Type {
+	case JzTypeNul:
+		return NewFromAny(0), nil
+	case JzTypeInt:
+		if n := in.data.(int64); n < 0 {
+			return NewFromAny(-n), nil
+		}
+		return in, nil
+	case JzTypeFlt:
+		return NewFromAny(math.Abs(in.data.(float64))), nil
+	case JzTypeStr:
+		return NewFromAny(utf8.RuneCountInString(in.data.(string))), nil
+	case JzTypeArr, JzTypeObj:
+		l, _ := in.Length()
+		return NewFromAny(l), nil
+	}
+
+	return nil, fmt.Errorf("%s has no length", jqTypeName(in))
+}
+
+func jqKeys(in *Jzon) (v *Jzon, err error) {
+	var keys = make([]*Jzon, 0)
+	switch in.Type {
+	case JzTypeObj:
+		for _, k := range in.sortedKeys() {
+			keys = append(keys, NewFromAny(k))
+		}
+	case JzTypeArr:
+		for i := range in.data.([]*Jzon) {
+			keys = append(keys, NewFromAny(i))
+		}
+	default:
+		return nil, fmt.Errorf("%s has no keys", jqTypeName(in))
+	}
+
+	return NewFromAny(keys), nil
+}
+
+func jqAdd(in *Jzon) (v *Jzon, err error) {
+	if in.Type != JzTypeArr && in.Type != JzTypeObj {
+		return nil, fmt.Errorf("cannot iterate over %s", jqTypeName(in))
+	}
+
+	arr := NewFromAny(in.children())
+	res, _ := arr.AReduce(New(JzTypeNul), func(a *Jzon, b Any) Any {
+		if err != nil {
+			return b
+		}
+		var sum *Jzon
+		if sum, err = binaryValue("+", b.(*Jzon), a); err != nil {
+			return b
+		}
+		return sum
+	})
+
+	return res.(*Jzon), err
+}
+
+func jqToNumber(in *Jzon) (v *Jzon, err error) {
+	if isNumber(in) {
+		return in, nil
+	}
+
+	if in.Type == JzTypeStr {
+		var n *Jzon
+		if n, _, err = parseNum([]byte(in.data.(string))); err == nil {
+			return n, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%s (%s) cannot be parsed as a number", jqTypeName(in), in.Compact())
+}
+
+func jqSort(in *Jzon) (v *Jzon, err error) {
+	if in.Type != JzTypeArr {
+		return nil, fmt.Errorf("%s cannot be sorted, as it is not an array", jqTypeName(in))
+	}
+
+	sorted := append(make([]*Jzon, 0), in.data.([]*Jzon)...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return Compare(sorted[i], sorted[j]) < 0
+	})
+
+	return NewFromAny(sorted), nil
+}
+
+func jqReverse(in *Jzon) (v *Jzon, err error) {
+	switch in.Type {
+	case JzTypeNul:
+		return NewFromAny(make([]*Jzon, 0)), nil
+	case JzTypeStr:
+		runes := []rune(in.data.(string))
+		for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+			runes[i], runes[j] = runes[j], runes[i]
+		}
+		return NewFromAny(string(runes)), nil
+	case JzTypeArr:
+		arr := in.data.([]*Jzon)
+		reversed := make([]*Jzon, len(arr))
+		for i, elem := range arr {
+			reversed[len(arr)-1-i] = elem
+		}
+		return NewFromAny(reversed), nil
+	}
+
+	return nil, fmt.Errorf("cannot reverse %s", jqTypeName(in))
+}
+
+func jqToEntries(in *Jzon) (v *Jzon, err error) {
+	if in.Type != JzTypeObj {
+		return nil, fmt.Errorf("%s has no keys", jqTypeName(in))
+	}
+
+	var entries = make([]*Jzon, 0)
+	for _, k := range in.sortedKeys() {
+		entry := New(JzTypeObj)
+		entry.Insert("key", NewFromAny(k))
+		entry.Insert("value", in.data.(map[string]*Jzon)[k])
+		entries = append(entries, entry)
+	}
+
+	return NewFromAny(entries), nil
+}
+
+func jqFromEntries(in *Jzon) (v *Jzon, err error) {
+	if in.Type != JzTypeArr {
+		return nil, fmt.Errorf("cannot iterate over %s", jqTypeName(in))
+	}
+
+	obj := New(JzTypeObj)
+	for _, entry := range in.data.([]*Jzon) {
+		var k, val *Jzon
+		for _, name := range []string{"key", "k", "name", "Name", "Key", "K"} {
+			if k, _ = indexValue(entry, NewFromAny(name)); k != nil && isTruthy(k) {
+				break
+			}
+		}
+		for _, name := range []string{"value", "v", "Value", "V"} {
+			if val, _ = indexValue(entry, NewFromAny(name)); val != nil && val.Type != JzTypeNul {
+				break
+			}
+		}
+
+		if k == nil || val == nil {
+			return nil, fmt.Errorf("cannot use %s (%s) as an entry", jqTypeName(entry), entry.Compact())
+		}
+		if k.Type != JzTypeStr && k.Type != JzTypeBol && !isNumber(k) {
+			return nil, fmt.Errorf("cannot use %s (%s) as object key", jqTypeName(k), k.Compact())
+		}
+		obj.Insert(jqToString(k), val)
+	}
+
+	return obj, nil
+}
+
+func jqSelect(in *Jzon, args []jqExpr) (outs []*Jzon, err error) {
+	conds, err := args[0].eval(in)
+	if err != nil {
+		return
+	}
+
+	for _, c := range conds {
+		if isTruthy(c) {
+			outs = append(outs, in)
+		}
+	}
+
+	return
+}
+
+func jqMap(in *Jzon, args []jqExpr) (outs []*Jzon, err error) {
+	apply := func(g *Jzon) Any {
+		if err != nil {
+			return nil
+		}
+		var vs []*Jzon
+		vs, err = args[0].eval(g)
+		return vs
+	}
+
+	var mapped []Any
+	switch in.Type {
+	case JzTypeArr:
+		mapped, _ = in.AMap(apply)
+	case JzTypeObj:
+		// OMap() visits values in the random order of the map, while jq visits them by sorted keys
+		for _, v := range in.children() {
+			mapped = append(mapped, apply(v))
+		}
+	default:
+		return nil, fmt.Errorf("cannot iterate over %s", jqTypeName(in))
+	}
+	if err != nil {
+		return
+	}
+
+	var res = make([]*Jzon, 0)
+	for _, vs := range mapped {
+		res = append(res, vs.([]*Jzon)...)
+	}
+
+	return []*Jzon{NewFromAny(res)}, nil
+}
+
+func jqHas(in *Jzon, args []jqExpr) (outs []*Jzon, err error) {
+	keys, err := args[0].eval(in)
+	if err != nil {
+		return
+	}
+
+	for _, k := range keys {
+		switch {
+		case in.Type == JzTypeObj && k.Type == JzTypeStr:
+			has, _ := in.Has(k.data.(string))
+			outs = append(outs, NewFromAny(has))
+		case in.Type == JzTypeArr && isNumber(k):
+			l, _ := in.Length()
+			outs = append(outs, NewFromAny(0 <= toFloat(k) && toFloat(k) < float64(l)))
+		default:
+			return nil, fmt.Errorf("cannot check whether %s has a %s key", jqTypeName(in), jqTypeName(k))
+		}
+	}
+
+	return
+}
+
+func jqWithEntries(in *Jzon, args []jqExpr) (outs []*Jzon, err error) {
+	entries, err := jqToEntries(in)
+	if err != nil {
+		return
+	}
+
+	mapped, err := jqMap(entries, args)
+	if err != nil {
+		return
+	}
+
+	obj, err := jqFromEntries(mapped[0])
+	if err != nil {
+		return
+	}
+
+	return []*Jzon{obj}, nil
+}
+
+// jqTypeName returns the type name of the node as jq names it
+func jqTypeName(jz *Jzon) string {
+	switch jz.Type {
+	case JzTypeStr:
+		return "string"
+	case JzTypeInt, JzTypeFlt:
+		return "number"
+	case JzTypeBol:
+		return "boolean"
+	case JzTypeObj:
+		return "object"
+	case JzTypeArr:
+		return "array"
+	}
+
+	return "null"
+}
+
+// jqToString returns strings themselves, and the compact text with sorted keys
+// for other types, so the output is stable
+func jqToString(jz *Jzon) string {
+	if jz.Type == JzTypeStr {
+		return jz.data.(string)
+	}
+
+	var buf bytes.Buffer
+	jz.writeSorted(&buf, false)
+	return buf.String()
+}
+
+func isTruthy(jz *Jzon) bool {
+	if jz.Type == JzTypeNul {
+		return false
+	}
+
+	if jz.Type == JzTypeBol {
+		return jz.data.(bool)
+	}
+
+	return true
+}
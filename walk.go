@@ -0,0 +1,151 @@
+package jzon
+
+// WalkAction tells the walker what to do after visiting a node
+type WalkAction int
+
+const (
+	// WalkContinue goes on walking, including children of the node
+	WalkContinue WalkAction = iota
+	// WalkSkipChildren goes on walking, but children of the node are skipped
+	WalkSkipChildren
+	// WalkStop stops walking immediately
+	WalkStop
+	// WalkDelete removes the node from its parent, it's only meaningful in `WalkMut()`
+	WalkDelete
+)
+
+// WalkFunc visits a node with the path from the root of the walk
+type WalkFunc func(path Path, node *Jzon) WalkAction
+
+// WalkMutFunc visits a node as WalkFunc does, and it can return a non-nil
+// node as the replacement of the visiting node
+type WalkMutFunc func(path Path, node *Jzon) (replacement *Jzon, action WalkAction)
+
+// Walk visits the node and all its descendants in pre-order, that is, a
+// node is visited before its children. elements of an array are visited
+// by indices, and values of an object are visited in sorted-key order
+func (jz *Jzon) Walk(fn WalkFunc) {
+	walkPreOrder(jz, nil, fn)
+}
+
+// WalkPostOrder performs as `Walk()`, except that a node is visited after its
+// children. since children have been visited, WalkSkipChildren is the same as
+// WalkContinue here
+func (jz *Jzon) WalkPostOrder(fn WalkFunc) {
+	walkPostOrder(jz, nil, fn)
+}
+
+// WalkMut walks as `Walk()`, and lets the visitor replace or delete the
+// node it is visiting. children of the replacement are walked instead of
+// the original ones. replacing the root node overwrites the node itself,
+// and deleting the root node turns it into null. paths passed to the
+// visitor are positions before the walk, deletions don't shift them
+func (jz *Jzon) WalkMut(fn WalkMutFunc) {
+	res, deleted, _ := walkMut(jz, nil, fn)
+	switch {
+	case deleted:
+		*jz = *New(JzTypeNul)
+	case res != jz:
+		*jz = *res
+	}
+}
+
+// pathOf copies segs, since the walker reuses the underlying array
+func pathOf(segs []segment) Path {
+	return Path{segs: append([]segment(nil), segs...)}
+}
+
+// eachChild calls fn on each child of the node with its path, until fn returns true
+func (jz *Jzon) eachChild(segs []segment, fn func(segs []segment, child *Jzon) (stop bool)) (stop bool) {
+	switch jz.Type {
+	case JzTypeArr:
+		for i, child := range jz.data.([]*Jzon) {
+			if fn(append(segs, segment{kind: _SegIndex, index: i}), child) {
+				return true
+			}
+		}
+
+	case JzTypeObj:
+		m := jz.data.(map[string]*Jzon)
+		for _, k := range jz.sortedKeys() {
+			if fn(append(segs, segment{kind: _SegKey, key: k}), m[k]) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func walkPreOrder(node *Jzon, segs []segment, fn WalkFunc) (stop bool) {
+	switch fn(pathOf(segs), node) {
+	case WalkStop:
+		return true
+	case WalkSkipChildren:
+		return false
+	}
+
+	return node.eachChild(segs, func(segs []segment, child *Jzon) bool {
+		return walkPreOrder(child, segs, fn)
+	})
+}
+
+func walkPostOrder(node *Jzon, segs []segment, fn WalkFunc) (stop bool) {
+	stop = node.eachChild(segs, func(segs []segment, child *Jzon) bool {
+		return walkPostOrder(child, segs, fn)
+	})
+
+	return stop || fn(pathOf(segs), node) == WalkStop
+}
+
+func walkMut(node *Jzon, segs []segment, fn WalkMutFunc) (res *Jzon, deleted bool, stop bool) {
+	replacement, action := fn(pathOf(segs), node)
+	if replacement != nil {
+		node = replacement
+	}
+
+	switch action {
+	case WalkDelete:
+		return nil, true, false
+	case WalkStop:
+		return node, false, true
+	case WalkSkipChildren:
+		return node, false, false
+	}
+
+	switch node.Type {
+	case JzTypeArr:
+		arr := node.data.([]*Jzon)
+		kept := make([]*Jzon, 0, len(arr))
+		for i, child := range arr {
+			if stop {
+				kept = append(kept, child)
+				continue
+			}
+
+			var del bool
+			child, del, stop = walkMut(child, append(segs, segment{kind: _SegIndex, index: i}), fn)
+			if !del {
+				kept = append(kept, child)
+			}
+		}
+		node.data = kept
+
+	case JzTypeObj:
+		m := node.data.(map[string]*Jzon)
+		for _, k := range node.sortedKeys() {
+			child, del, s := walkMut(m[k], append(segs, segment{kind: _SegKey, key: k}), fn)
+			if del {
+				delete(m, k)
+			} else {
+				m[k] = child
+			}
+
+			if stop = s; stop {
+				break
+			}
+		}
+	}
+
+	return node, false, stop
+}